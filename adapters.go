@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+import "gopkg.in/yaml.v3"
+
+const adaptersFilename = "adapters.yaml"
+
+const (
+	promptViaStdin = "stdin"
+	promptViaArg   = "arg"
+	promptViaFile  = "file"
+)
+
+const (
+	promptArgPlaceholder     = "{{prompt}}"
+	promptFileArgPlaceholder = "{{prompt_file}}"
+)
+
+type cliAdapter struct {
+	Name            string              `yaml:"-"`
+	Binary          string              `yaml:"binary,omitempty"`
+	Version         string              `yaml:"version,omitempty"`
+	Args            []string            `yaml:"args,omitempty"`
+	Prompt          string              `yaml:"prompt,omitempty"`
+	Strip           []string            `yaml:"strip,omitempty"`
+	StripCodeFences bool                `yaml:"strip_code_fences,omitempty"`
+	Capabilities    adapterCapabilities `yaml:"capabilities"`
+
+	stripPatterns []*regexp.Regexp
+}
+
+type adapterCapabilities struct {
+	NonInteractive bool `yaml:"non_interactive"`
+}
+
+// UnmarshalYAML defaults capabilities.non_interactive to true for adapters
+// from adapters.yaml; beet only ever runs them non-interactively, so a profile
+// has to opt out explicitly.
+func (a *cliAdapter) UnmarshalYAML(value *yaml.Node) error {
+	type plain cliAdapter
+	decoded := plain{Capabilities: adapterCapabilities{NonInteractive: true}}
+	if err := value.Decode(&decoded); err != nil {
+		return err
+	}
+	*a = cliAdapter(decoded)
+	return nil
+}
+
+type adapterRegistry struct {
+	priority []string
+	adapters map[string]cliAdapter
+}
+
+type adaptersFile struct {
+	Priority []string              `yaml:"priority"`
+	Adapters map[string]cliAdapter `yaml:"adapters"`
+}
+
+var builtinAdapters = map[string]cliAdapter{
+	"codex": {
+		Version:      "1",
+		Args:         []string{"exec", "--skip-git-repo-check", "-"},
+		Prompt:       promptViaStdin,
+		Capabilities: adapterCapabilities{NonInteractive: true},
+	},
+	"copilot": {
+		Version:         "1",
+		Args:            []string{"-p", promptArgPlaceholder},
+		Prompt:          promptViaArg,
+		StripCodeFences: true,
+		Capabilities:    adapterCapabilities{NonInteractive: true},
+	},
+	"claude": {
+		Version:      "1",
+		Args:         []string{"-p"},
+		Prompt:       promptViaStdin,
+		Capabilities: adapterCapabilities{NonInteractive: true},
+	},
+}
+
+var genericAdapter = cliAdapter{
+	Version:      "1",
+	Prompt:       promptViaStdin,
+	Capabilities: adapterCapabilities{NonInteractive: true},
+}
+
+func newAdapterRegistry() adapterRegistry {
+	reg := adapterRegistry{
+		priority: append([]string(nil), cliPriority...),
+		adapters: make(map[string]cliAdapter, len(builtinAdapters)),
+	}
+	for name, a := range builtinAdapters {
+		a.Name = name
+		reg.adapters[name] = a
+	}
+	return reg
+}
+
+func loadAdapters(configDir string) (adapterRegistry, error) {
+	reg := newAdapterRegistry()
+
+	path := filepath.Join(configDir, adaptersFilename)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return reg, reg.compile()
+	}
+	if err != nil {
+		return adapterRegistry{}, fmt.Errorf("read adapters: %w", err)
+	}
+
+	var file adaptersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return adapterRegistry{}, fmt.Errorf("parse %s: %w", adaptersFilename, err)
+	}
+
+	for name, a := range file.Adapters {
+		a.Name = name
+		if a.Version == "" {
+			a.Version = "1"
+		}
+		if a.Prompt == "" {
+			a.Prompt = promptViaStdin
+		}
+		reg.adapters[name] = a
+		logVerbose("loaded adapter %s from %s", name, path)
+	}
+
+	if len(file.Priority) > 0 {
+		reg.priority = file.Priority
+	} else {
+		var custom []string
+		for name := range file.Adapters {
+			if _, ok := builtinAdapters[name]; !ok {
+				custom = append(custom, name)
+			}
+		}
+		sort.Strings(custom)
+		reg.priority = append(reg.priority, custom...)
+	}
+
+	for _, name := range reg.priority {
+		if _, ok := reg.adapters[name]; !ok {
+			return adapterRegistry{}, fmt.Errorf("%s: priority lists unknown adapter %s", adaptersFilename, name)
+		}
+	}
+
+	return reg, reg.compile()
+}
+
+func (r adapterRegistry) compile() error {
+	for name, a := range r.adapters {
+		switch a.Prompt {
+		case promptViaStdin, promptViaArg, promptViaFile:
+		default:
+			return fmt.Errorf("adapter %s: unknown prompt mode %q (want stdin, arg, or file)", name, a.Prompt)
+		}
+
+		a.stripPatterns = nil
+		for _, pattern := range a.Strip {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("adapter %s: strip pattern %q: %w", name, pattern, err)
+			}
+			a.stripPatterns = append(a.stripPatterns, re)
+		}
+		r.adapters[name] = a
+	}
+	return nil
+}
+
+func (r adapterRegistry) binary(name string) string {
+	if a, ok := r.adapters[name]; ok && a.Binary != "" {
+		return a.Binary
+	}
+	return name
+}
+
+func (r adapterRegistry) lookup(name string) cliAdapter {
+	if a, ok := r.adapters[name]; ok {
+		return a
+	}
+	a := genericAdapter
+	a.Name = name
+	return a
+}
+
+func (r adapterRegistry) detectAll() []detectedCLI {
+	var out []detectedCLI
+	for _, name := range r.priority {
+		if cli, ok := lookupCLI(name, r.binary(name)); ok {
+			out = append(out, cli)
+		}
+	}
+	return out
+}
+
+func (r adapterRegistry) require() (detectedCLI, cliAdapter, error) {
+	if override, ok, err := detectCLIOverride(); err != nil {
+		return detectedCLI{}, cliAdapter{}, err
+	} else if ok {
+		logVerbose("using CLI override %s (%s)", override.name, override.path)
+		return override, r.lookup(override.name), nil
+	}
+
+	found := r.detectAll()
+	if len(found) == 0 {
+		return detectedCLI{}, cliAdapter{}, fmt.Errorf("no supported CLI found; install Codex CLI, Copilot CLI, or Claude Code CLI")
+	}
+	logVerbose("selected CLI %s (%s)", found[0].name, found[0].path)
+	return found[0], r.lookup(found[0].name), nil
+}
+
+func (a cliAdapter) command(prompt, promptFile string) []string {
+	args := make([]string, 0, len(a.Args)+1)
+	hasPrompt := false
+	for _, arg := range a.Args {
+		if strings.Contains(arg, promptArgPlaceholder) || strings.Contains(arg, promptFileArgPlaceholder) {
+			hasPrompt = true
+		}
+		arg = strings.ReplaceAll(arg, promptArgPlaceholder, prompt)
+		arg = strings.ReplaceAll(arg, promptFileArgPlaceholder, promptFile)
+		args = append(args, arg)
+	}
+
+	if !hasPrompt {
+		switch a.Prompt {
+		case promptViaArg:
+			args = append(args, prompt)
+		case promptViaFile:
+			args = append(args, promptFile)
+		}
+	}
+	return args
+}
+
+func (a cliAdapter) clean(output string) string {
+	if len(a.stripPatterns) > 0 {
+		lines := strings.Split(output, "\n")
+		kept := lines[:0]
+		for _, line := range lines {
+			drop := false
+			for _, re := range a.stripPatterns {
+				if re.MatchString(line) {
+					drop = true
+					break
+				}
+			}
+			if !drop {
+				kept = append(kept, line)
+			}
+		}
+		output = strings.Join(kept, "\n")
+	}
+
+	if a.StripCodeFences {
+		output = unwrapCodeFence(output)
+	}
+	return output
+}
+
+// unwrapCodeFence removes a single fence that wraps the entire output, as
+// chat-oriented CLIs tend to add around Markdown answers.
+func unwrapCodeFence(output string) string {
+	trimmed := strings.TrimSpace(output)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") {
+		return output
+	}
+	lines := strings.Split(trimmed, "\n")
+	if len(lines) < 2 {
+		return output
+	}
+	inner := lines[1 : len(lines)-1]
+	for _, line := range inner {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			return output
+		}
+	}
+	return strings.Join(inner, "\n")
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeAdaptersFile(t *testing.T, content string) string {
+	t.Helper()
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, adaptersFilename), []byte(content), 0o644); err != nil {
+		t.Fatalf("write adapters: %v", err)
+	}
+	return configDir
+}
+
+func TestLoadAdaptersDefaults(t *testing.T) {
+	reg, err := loadAdapters(t.TempDir())
+	if err != nil {
+		t.Fatalf("loadAdapters returned error: %v", err)
+	}
+	if !reflect.DeepEqual(reg.priority, cliPriority) {
+		t.Fatalf("priority = %v, want %v", reg.priority, cliPriority)
+	}
+	for _, name := range cliPriority {
+		a := reg.lookup(name)
+		if a.Name != name || !a.Capabilities.NonInteractive {
+			t.Fatalf("builtin adapter %s = %+v", name, a)
+		}
+	}
+}
+
+func TestLoadAdaptersCustomWrapper(t *testing.T) {
+	configDir := writeAdaptersFile(t, `priority: [acme, claude]
+adapters:
+  acme:
+    binary: acme-llm
+    args: ["run", "--quiet", "{{prompt_file}}"]
+    prompt: file
+    strip: ["^Acme v[0-9]"]
+    strip_code_fences: true
+    capabilities:
+      non_interactive: true
+`)
+
+	reg, err := loadAdapters(configDir)
+	if err != nil {
+		t.Fatalf("loadAdapters returned error: %v", err)
+	}
+	if !reflect.DeepEqual(reg.priority, []string{"acme", "claude"}) {
+		t.Fatalf("priority = %v", reg.priority)
+	}
+	if reg.binary("acme") != "acme-llm" || reg.binary("claude") != "claude" {
+		t.Fatalf("unexpected binaries: %s, %s", reg.binary("acme"), reg.binary("claude"))
+	}
+
+	a := reg.lookup("acme")
+	if a.Version != "1" {
+		t.Fatalf("version default = %q, want 1", a.Version)
+	}
+	if got := a.command("hi", "/tmp/p.md"); !reflect.DeepEqual(got, []string{"run", "--quiet", "/tmp/p.md"}) {
+		t.Fatalf("command = %v", got)
+	}
+	if got := a.clean("Acme v2.1 ready\n```markdown\n# Plan\n```\n"); got != "# Plan" {
+		t.Fatalf("clean = %q, want # Plan", got)
+	}
+}
+
+func TestLoadAdaptersDefaultsNonInteractive(t *testing.T) {
+	configDir := writeAdaptersFile(t, "adapters:\n  plain: {}\n  partial:\n    capabilities: {}\n  manual:\n    capabilities:\n      non_interactive: false\n")
+
+	reg, err := loadAdapters(configDir)
+	if err != nil {
+		t.Fatalf("loadAdapters returned error: %v", err)
+	}
+	for name, want := range map[string]bool{"plain": true, "partial": true, "manual": false} {
+		if got := reg.lookup(name).Capabilities.NonInteractive; got != want {
+			t.Fatalf("%s non_interactive = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestLoadAdaptersAppendsUnlistedCustomAdapters(t *testing.T) {
+	configDir := writeAdaptersFile(t, "adapters:\n  zeta: {}\n  alpha: {}\n")
+
+	reg, err := loadAdapters(configDir)
+	if err != nil {
+		t.Fatalf("loadAdapters returned error: %v", err)
+	}
+	want := append(append([]string(nil), cliPriority...), "alpha", "zeta")
+	if !reflect.DeepEqual(reg.priority, want) {
+		t.Fatalf("priority = %v, want %v", reg.priority, want)
+	}
+	if reg.lookup("alpha").Prompt != promptViaStdin {
+		t.Fatalf("custom adapter should default to stdin prompts")
+	}
+}
+
+func TestLoadAdaptersValidates(t *testing.T) {
+	cases := map[string]string{
+		"adapters:\n  x:\n    prompt: socket\n": "unknown prompt mode",
+		"adapters:\n  x:\n    strip: ['(']\n":   "strip pattern",
+		"priority: [missing]\n":                 "unknown adapter missing",
+		"adapters: [":                           "parse adapters.yaml",
+	}
+	for content, want := range cases {
+		_, err := loadAdapters(writeAdaptersFile(t, content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("loadAdapters(%q) error = %v, want %q", content, err, want)
+		}
+	}
+}
+
+func TestAdapterCommandPromptModes(t *testing.T) {
+	arg := cliAdapter{Args: []string{"-p", "{{prompt}}"}, Prompt: promptViaArg}
+	if got := arg.command("hello", ""); !reflect.DeepEqual(got, []string{"-p", "hello"}) {
+		t.Fatalf("arg command = %v", got)
+	}
+
+	appended := cliAdapter{Args: []string{"ask"}, Prompt: promptViaArg}
+	if got := appended.command("hello", ""); !reflect.DeepEqual(got, []string{"ask", "hello"}) {
+		t.Fatalf("appended command = %v", got)
+	}
+
+	stdin := cliAdapter{Args: []string{"-p"}, Prompt: promptViaStdin}
+	if got := stdin.command("hello", ""); !reflect.DeepEqual(got, []string{"-p"}) {
+		t.Fatalf("stdin command = %v", got)
+	}
+}
+
+func TestUnwrapCodeFenceKeepsInnerFences(t *testing.T) {
+	in := "```\nfirst\n```\ntext\n```\nsecond\n```"
+	if got := unwrapCodeFence(in); got != in {
+		t.Fatalf("unwrapCodeFence should keep multi-fence output, got %q", got)
+	}
+}
+
+func TestCLIBackendUsesAdapterInvocation(t *testing.T) {
+	cli := writeFakeCLI(t, "acme", `echo "banner"; echo "args:$1"; cat "$2"`)
+	reg, err := loadAdapters(writeAdaptersFile(t, `adapters:
+  acme:
+    args: ["run", "{{prompt_file}}"]
+    prompt: file
+    strip: ["^banner$"]
+`))
+	if err != nil {
+		t.Fatalf("loadAdapters returned error: %v", err)
+	}
+
+	b := cliBackend{cli: cli, adapter: reg.lookup("acme")}
+	got, err := b.complete(context.Background(), "from file")
+	if err != nil {
+		t.Fatalf("complete returned error: %v", err)
+	}
+	if got != "args:run\nfrom file" {
+		t.Fatalf("complete = %q", got)
+	}
+}
+
+func TestRunDoctorListsCustomAdapters(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "acme-llm"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatalf("write acme: %v", err)
+	}
+	t.Setenv("PATH", binDir)
+	t.Setenv(envCLIBinary, "")
+
+	configDir := writeAdaptersFile(t, "priority: [acme]\nadapters:\n  acme:\n    binary: acme-llm\n")
+
+	var b strings.Builder
+	if err := runDoctor(&b, configDir); err != nil {
+		t.Fatalf("runDoctor error: %v", err)
+	}
+	out := b.String()
+	if !strings.Contains(out, "acme: found at "+filepath.Join(binDir, "acme-llm")+" (adapter v1, prompt via stdin)") {
+		t.Fatalf("doctor output missing custom adapter: %s", out)
+	}
+	if strings.Contains(out, "No supported CLI") {
+		t.Fatalf("doctor should not warn when custom adapter found: %s", out)
+	}
+}
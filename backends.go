@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	envFixturesDir     = "BEET_FIXTURES_DIR"
+	fixturesDirName    = "fixtures"
+	echoBackendName    = "echo"
+	fixtureBackendName = "fixture"
+)
+
+type backendOptions struct {
+	cli      string
+	record   bool
+	noCache  bool
+	cacheTTL time.Duration
+}
+
+type echoBackend struct{}
+
+func (echoBackend) name() string {
+	return echoBackendName
+}
+
+func (echoBackend) complete(_ context.Context, prompt string) (string, error) {
+	return prompt, nil
+}
+
+type fixtureBackend struct {
+	dir string
+}
+
+func (fixtureBackend) name() string {
+	return fixtureBackendName
+}
+
+func (b fixtureBackend) complete(_ context.Context, prompt string) (string, error) {
+	path := fixturePath(b.dir, prompt)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("no fixture %s; record one with --record-fixtures", path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read fixture: %w", err)
+	}
+	return string(data), nil
+}
+
+type recordingBackend struct {
+	inner llmBackend
+	dir   string
+}
+
+func (b recordingBackend) name() string {
+	return b.inner.name()
+}
+
+func (b recordingBackend) complete(ctx context.Context, prompt string) (string, error) {
+	out, err := b.inner.complete(ctx, prompt)
+	if err != nil || strings.TrimSpace(out) == "" {
+		return out, err
+	}
+
+	path := fixturePath(b.dir, prompt)
+	if err := writeFileAtomic(path, []byte(out)); err != nil {
+		return "", fmt.Errorf("record fixture: %w", err)
+	}
+	logVerbose("recorded fixture %s", path)
+	return out, nil
+}
+
+func sha256Hex(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+func fixturePath(dir, prompt string) string {
+	return filepath.Join(dir, sha256Hex(prompt)+".md")
+}
+
+func fixturesDir(configDir string) (string, error) {
+	if override := strings.TrimSpace(os.Getenv(envFixturesDir)); override != "" {
+		return filepath.Abs(override)
+	}
+	return filepath.Join(configDir, fixturesDirName), nil
+}
+
+func isBuiltinBackend(name string) bool {
+	return name == echoBackendName || name == fixtureBackendName
+}
+
+func builtinBackend(configDir, name string) (llmBackend, error) {
+	switch name {
+	case echoBackendName:
+		return echoBackend{}, nil
+	case fixtureBackendName:
+		dir, err := fixturesDir(configDir)
+		if err != nil {
+			return nil, err
+		}
+		return fixtureBackend{dir: dir}, nil
+	}
+	return nil, fmt.Errorf("unknown built-in backend %s", name)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEchoBackendReturnsPrompt(t *testing.T) {
+	got, err := echoBackend{}.complete(context.Background(), "same text")
+	if err != nil || got != "same text" {
+		t.Fatalf("echo complete = %q, %v", got, err)
+	}
+}
+
+func TestFixtureBackendReplaysByPromptHash(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, sha256Hex("prompt one")+".md"), []byte("canned"), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	b := fixtureBackend{dir: dir}
+	got, err := b.complete(context.Background(), "prompt one")
+	if err != nil || got != "canned" {
+		t.Fatalf("fixture complete = %q, %v", got, err)
+	}
+
+	if _, err := b.complete(context.Background(), "prompt two"); err == nil || !strings.Contains(err.Error(), "no fixture") {
+		t.Fatalf("missing fixture error = %v", err)
+	}
+}
+
+func TestRecordingBackendWritesFixtures(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "fixtures")
+	cli := writeFakeCLI(t, "codex", "tr a-z A-Z")
+	b := recordingBackend{inner: cliBackend{cli: cli, adapter: genericAdapter}, dir: dir}
+
+	got, err := b.complete(context.Background(), "record me")
+	if err != nil || got != "RECORD ME" {
+		t.Fatalf("recording complete = %q, %v", got, err)
+	}
+
+	replayed, err := fixtureBackend{dir: dir}.complete(context.Background(), "record me")
+	if err != nil || replayed != "RECORD ME" {
+		t.Fatalf("replay = %q, %v", replayed, err)
+	}
+}
+
+func TestSelectBackendHonorsBuiltinOverride(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv(envCLIBinary, fixtureBackendName)
+	t.Setenv(envFixturesDir, "")
+
+	backend, err := selectBackendFn(configDir, backendOptions{})
+	if err != nil {
+		t.Fatalf("select backend: %v", err)
+	}
+	fixture, ok := backend.(fixtureBackend)
+	if !ok {
+		t.Fatalf("backend = %T, want fixtureBackend", backend)
+	}
+	if fixture.dir != filepath.Join(configDir, fixturesDirName) {
+		t.Fatalf("fixture dir = %s", fixture.dir)
+	}
+
+	backend, err = selectBackendFn(configDir, backendOptions{cli: echoBackendName, record: true})
+	if err != nil {
+		t.Fatalf("select backend: %v", err)
+	}
+	if _, ok := backend.(recordingBackend); !ok {
+		t.Fatalf("backend = %T, want recordingBackend", backend)
+	}
+}
+
+func TestSelectBackendByAdapterName(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "claude"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatalf("write claude: %v", err)
+	}
+	t.Setenv("PATH", binDir)
+	t.Setenv(envCLIBinary, "")
+
+	backend, err := selectBackendFn(t.TempDir(), backendOptions{cli: "claude"})
+	if err != nil {
+		t.Fatalf("select backend: %v", err)
+	}
+	if backend.name() != "claude" {
+		t.Fatalf("backend name = %s, want claude", backend.name())
+	}
+
+	if _, err := selectBackendFn(t.TempDir(), backendOptions{cli: "codex"}); err == nil {
+		t.Fatalf("expected error for missing codex")
+	}
+}
+
+func TestRefinerWithEchoBackend(t *testing.T) {
+	r := &refiner{backend: echoBackend{}, timeout: time.Second}
+	if got, err := r.refine("PRD.md", "  body  "); err != nil || got != "body\n" {
+		t.Fatalf("refine = %q", got)
+	}
+}
+
+func TestRunDoctorReportsBuiltinOverride(t *testing.T) {
+	t.Setenv("PATH", "")
+	t.Setenv(envCLIBinary, echoBackendName)
+
+	var b strings.Builder
+	if err := runDoctor(&b, t.TempDir()); err != nil {
+		t.Fatalf("runDoctor error: %v", err)
+	}
+	if !strings.Contains(b.String(), envCLIBinary+" override: echo (built-in backend)") {
+		t.Fatalf("doctor output missing built-in override: %s", b.String())
+	}
+}
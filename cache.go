@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	cacheDirName    = "cache"
+	envCacheTTL     = "BEET_CACHE_TTL"
+	defaultCacheTTL = 7 * 24 * time.Hour
+)
+
+var cacheNow = time.Now
+
+type cacheEntry struct {
+	Key            string    `json:"key"`
+	Backend        string    `json:"backend"`
+	AdapterVersion string    `json:"adapter_version"`
+	PromptHash     string    `json:"prompt_hash"`
+	Created        time.Time `json:"created"`
+	Response       string    `json:"response"`
+}
+
+type responseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+func newResponseCache(configDir string, ttl time.Duration) responseCache {
+	return responseCache{dir: filepath.Join(configDir, cacheDirName), ttl: ttl}
+}
+
+func cacheKey(backend, adapterVersion, prompt string) string {
+	sum := sha256.Sum256([]byte(backend + "\x00" + adapterVersion + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+func cacheTTL(flagValue string) (time.Duration, error) {
+	raw := strings.TrimSpace(flagValue)
+	source := "--cache-ttl"
+	if raw == "" {
+		raw = strings.TrimSpace(os.Getenv(envCacheTTL))
+		source = envCacheTTL
+	}
+	if raw == "" {
+		return defaultCacheTTL, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", source, raw, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be positive", source, raw)
+	}
+	return d, nil
+}
+
+func (c responseCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (e cacheEntry) expired(ttl time.Duration) bool {
+	return cacheNow().Sub(e.Created) > ttl
+}
+
+func (c responseCache) get(key string) (string, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return "", false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		logVerbose("ignoring unreadable cache entry %s: %v", key, err)
+		return "", false
+	}
+	if entry.expired(c.ttl) {
+		logVerbose("cache entry %s expired", key)
+		return "", false
+	}
+	return entry.Response, true
+}
+
+func (c responseCache) put(entry cacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode cache entry: %w", err)
+	}
+	if err := writeFileAtomic(c.path(entry.Key), data); err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	return nil
+}
+
+func (c responseCache) entries() ([]cacheEntry, []int64, error) {
+	files, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("read cache: %w", err)
+	}
+
+	var entries []cacheEntry
+	var sizes []int64
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.dir, f.Name()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("read cache entry %s: %w", f.Name(), err)
+		}
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			entry = cacheEntry{Key: strings.TrimSuffix(f.Name(), ".json")}
+		}
+		entries = append(entries, entry)
+		sizes = append(sizes, int64(len(data)))
+	}
+	return entries, sizes, nil
+}
+
+type cachingBackend struct {
+	inner   llmBackend
+	version string
+	cache   responseCache
+}
+
+func (b cachingBackend) name() string {
+	return b.inner.name()
+}
+
+func (b cachingBackend) complete(ctx context.Context, prompt string) (string, error) {
+	key := cacheKey(b.inner.name(), b.version, prompt)
+	if out, ok := b.cache.get(key); ok {
+		logVerbose("cache hit %s for %s", key[:12], b.inner.name())
+		return out, nil
+	}
+
+	out, err := b.inner.complete(ctx, prompt)
+	if err != nil || strings.TrimSpace(out) == "" {
+		return out, err
+	}
+
+	entry := cacheEntry{
+		Key:            key,
+		Backend:        b.inner.name(),
+		AdapterVersion: b.version,
+		PromptHash:     sha256Hex(prompt),
+		Created:        cacheNow().UTC(),
+		Response:       out,
+	}
+	if err := b.cache.put(entry); err != nil {
+		logWarning("%v", err)
+	}
+	return out, nil
+}
+
+func handleCacheCommand(configDir string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: beet cache [ls|clear|stats]")
+	}
+
+	fs := flag.NewFlagSet("cache "+args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	ttlFlag := fs.String("ttl", "", "entry lifetime used to report expiry (default $"+envCacheTTL+" or 168h)")
+	expiredOnly := false
+	if args[0] == "clear" {
+		fs.BoolVar(&expiredOnly, "expired", false, "only remove expired entries")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	ttl, err := cacheTTL(*ttlFlag)
+	if err != nil {
+		return err
+	}
+	c := newResponseCache(configDir, ttl)
+
+	switch args[0] {
+	case "ls":
+		return listCache(os.Stdout, c)
+	case "clear":
+		return clearCache(os.Stdout, c, expiredOnly)
+	case "stats":
+		return cacheStats(os.Stdout, c)
+	default:
+		return fmt.Errorf("usage: beet cache [ls|clear|stats]")
+	}
+}
+
+func listCache(w io.Writer, c responseCache) error {
+	entries, sizes, err := c.entries()
+	if err != nil {
+		return err
+	}
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return entries[order[i]].Created.After(entries[order[j]].Created)
+	})
+
+	for _, i := range order {
+		e := entries[i]
+		state := "fresh"
+		if e.expired(c.ttl) {
+			state = "expired"
+		}
+		key := e.Key
+		if len(key) > 12 {
+			key = key[:12]
+		}
+		age := cacheNow().Sub(e.Created).Round(time.Second)
+		if _, err := fmt.Fprintf(w, "%s  %-8s v%-3s %8d B  %-10s %s\n", key, e.Backend, e.AdapterVersion, sizes[i], age, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func clearCache(w io.Writer, c responseCache, expiredOnly bool) error {
+	entries, _, err := c.entries()
+	if err != nil {
+		return err
+	}
+	removed := 0
+	for _, e := range entries {
+		if expiredOnly && !e.expired(c.ttl) {
+			continue
+		}
+		if err := os.Remove(c.path(e.Key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove cache entry: %w", err)
+		}
+		removed++
+	}
+	_, err = fmt.Fprintf(w, "removed %d cache entries\n", removed)
+	return err
+}
+
+func cacheStats(w io.Writer, c responseCache) error {
+	entries, sizes, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	expired := 0
+	byBackend := make(map[string]int)
+	for i, e := range entries {
+		total += sizes[i]
+		if e.expired(c.ttl) {
+			expired++
+		}
+		byBackend[e.Backend]++
+	}
+
+	lines := []string{
+		fmt.Sprintf("dir: %s", c.dir),
+		fmt.Sprintf("entries: %d (%d expired, ttl %s)", len(entries), expired, c.ttl),
+		fmt.Sprintf("size: %d bytes", total),
+	}
+	backends := make([]string, 0, len(byBackend))
+	for name := range byBackend {
+		backends = append(backends, name)
+	}
+	sort.Strings(backends)
+	for _, name := range backends {
+		lines = append(lines, fmt.Sprintf("backend %s: %d", name, byBackend[name]))
+	}
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func countingCLI(t *testing.T) (detectedCLI, string) {
+	t.Helper()
+	calls := filepath.Join(t.TempDir(), "calls")
+	return writeFakeCLI(t, "codex", "echo x >> "+calls+"\ntr a-z A-Z"), calls
+}
+
+func callCount(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("read calls: %v", err)
+	}
+	return strings.Count(string(data), "x")
+}
+
+func TestCacheTTL(t *testing.T) {
+	t.Setenv(envCacheTTL, "")
+	if d, err := cacheTTL(""); err != nil || d != defaultCacheTTL {
+		t.Fatalf("default ttl = %s, %v", d, err)
+	}
+
+	t.Setenv(envCacheTTL, "2h")
+	if d, err := cacheTTL(""); err != nil || d != 2*time.Hour {
+		t.Fatalf("env ttl = %s, %v", d, err)
+	}
+	if d, err := cacheTTL("30m"); err != nil || d != 30*time.Minute {
+		t.Fatalf("flag ttl = %s, %v", d, err)
+	}
+
+	for _, bad := range []string{"soon", "0s", "-1h"} {
+		if _, err := cacheTTL(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestCachingBackendReusesResponses(t *testing.T) {
+	cli, calls := countingCLI(t)
+	cache := newResponseCache(t.TempDir(), time.Hour)
+	b := cachingBackend{inner: cliBackend{cli: cli, adapter: genericAdapter}, version: "1", cache: cache}
+
+	for i := 0; i < 2; i++ {
+		got, err := b.complete(context.Background(), "same prompt")
+		if err != nil || got != "SAME PROMPT" {
+			t.Fatalf("complete #%d = %q, %v", i, got, err)
+		}
+	}
+	if n := callCount(t, calls); n != 1 {
+		t.Fatalf("CLI calls = %d, want 1", n)
+	}
+
+	if _, err := b.complete(context.Background(), "other prompt"); err != nil {
+		t.Fatalf("complete other: %v", err)
+	}
+	bumped := b
+	bumped.version = "2"
+	if _, err := bumped.complete(context.Background(), "same prompt"); err != nil {
+		t.Fatalf("complete bumped: %v", err)
+	}
+	if n := callCount(t, calls); n != 3 {
+		t.Fatalf("CLI calls = %d, want 3 (new prompt and new adapter version miss)", n)
+	}
+}
+
+func TestCachingBackendExpiresEntries(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cacheNow = func() time.Time { return now }
+	t.Cleanup(func() { cacheNow = time.Now })
+
+	cli, calls := countingCLI(t)
+	b := cachingBackend{inner: cliBackend{cli: cli, adapter: genericAdapter}, version: "1", cache: newResponseCache(t.TempDir(), time.Hour)}
+
+	if _, err := b.complete(context.Background(), "prompt"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	if _, err := b.complete(context.Background(), "prompt"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if n := callCount(t, calls); n != 2 {
+		t.Fatalf("CLI calls = %d, want 2 after expiry", n)
+	}
+}
+
+func TestCachingBackendSkipsFailures(t *testing.T) {
+	cli := writeFakeCLI(t, "codex", "exit 1")
+	cache := newResponseCache(t.TempDir(), time.Hour)
+	b := cachingBackend{inner: cliBackend{cli: cli, adapter: genericAdapter}, version: "1", cache: cache}
+
+	if _, err := b.complete(context.Background(), "prompt"); err == nil {
+		t.Fatalf("expected error from failing CLI")
+	}
+	entries, _, err := cache.entries()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("entries = %v, %v; want none", entries, err)
+	}
+}
+
+func TestSelectBackendWrapsCLIWithCache(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "claude"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatalf("write claude: %v", err)
+	}
+	t.Setenv("PATH", binDir)
+	t.Setenv(envCLIBinary, "")
+
+	backend, err := selectBackendFn(t.TempDir(), backendOptions{cli: "claude", cacheTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("select backend: %v", err)
+	}
+	if _, ok := backend.(cachingBackend); !ok {
+		t.Fatalf("backend = %T, want cachingBackend", backend)
+	}
+
+	backend, err = selectBackendFn(t.TempDir(), backendOptions{cli: "claude", noCache: true})
+	if err != nil {
+		t.Fatalf("select backend: %v", err)
+	}
+	if _, ok := backend.(cliBackend); !ok {
+		t.Fatalf("backend = %T, want cliBackend with --no-cache", backend)
+	}
+}
+
+func TestCacheCommandsListStatsAndClear(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cacheNow = func() time.Time { return now }
+	t.Cleanup(func() { cacheNow = time.Now })
+
+	c := newResponseCache(t.TempDir(), time.Hour)
+	ages := map[string]time.Duration{"old": 2 * time.Hour, "new": 10 * time.Minute}
+	for prompt, age := range ages {
+		entry := cacheEntry{
+			Key:            cacheKey("codex", "1", prompt),
+			Backend:        "codex",
+			AdapterVersion: "1",
+			Created:        now.Add(-age),
+			Response:       prompt,
+		}
+		if err := c.put(entry); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+
+	var ls strings.Builder
+	if err := listCache(&ls, c); err != nil {
+		t.Fatalf("listCache: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(ls.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "fresh") || !strings.HasSuffix(lines[1], "expired") {
+		t.Fatalf("ls output unexpected:\n%s", ls.String())
+	}
+
+	var stats strings.Builder
+	if err := cacheStats(&stats, c); err != nil {
+		t.Fatalf("cacheStats: %v", err)
+	}
+	if !strings.Contains(stats.String(), "entries: 2 (1 expired, ttl 1h0m0s)") || !strings.Contains(stats.String(), "backend codex: 2") {
+		t.Fatalf("stats output unexpected:\n%s", stats.String())
+	}
+
+	var out strings.Builder
+	if err := clearCache(&out, c, true); err != nil {
+		t.Fatalf("clear expired: %v", err)
+	}
+	if _, ok := c.get(cacheKey("codex", "1", "new")); !ok {
+		t.Fatalf("fresh entry removed by clear --expired")
+	}
+	if err := clearCache(&out, c, false); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if entries, _, _ := c.entries(); len(entries) != 0 {
+		t.Fatalf("entries after clear = %d", len(entries))
+	}
+	if !strings.Contains(out.String(), "removed 1 cache entries\nremoved 1 cache entries") {
+		t.Fatalf("clear output = %q", out.String())
+	}
+}
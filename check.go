@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Check statuses, as they appear in the report.
+const (
+	checkOK      = "ok"
+	checkMissing = "missing"
+	checkStale   = "stale"
+)
+
+type checkReport struct {
+	OK      bool          `json:"ok"`
+	Outputs []checkResult `json:"outputs"`
+}
+
+type checkResult struct {
+	File    string `json:"file"`
+	Status  string `json:"status"`
+	Added   int    `json:"added,omitempty"`
+	Removed int    `json:"removed,omitempty"`
+	// ChangedInputs names the inputs that differ from the manifest, when
+	// there is one.
+	ChangedInputs []string `json:"changed_inputs,omitempty"`
+	// Refined outputs are checked against the manifest's hashes, so they
+	// have no line counts.
+	Refined bool `json:"refined,omitempty"`
+}
+
+// handleCheck renders the pack from a committed intent, or the one recorded in
+// the manifest, and fails when writing it would change any output, so CI can
+// catch files nobody regenerated.
+func handleCheck(configDir string, args []string) error {
+	fs, opts := newGenerateFlags("check")
+	format := fs.String("format", "text", "report format: text or json")
+	fs.Usage = func() {
+		usagePrintln(fs.Output(), "Usage: beet check [--format text|json] [generate flags] [--intent-file FILE]")
+		usagePrintln(fs.Output(), "\nExits non-zero when any output is missing or differs from what generation would write.")
+		usagePrintln(fs.Output(), "Without an intent, replays the pack, options and intent recorded in "+manifestPath()+";")
+		usagePrintln(fs.Output(), "flags given on the command line take precedence, and --set adds to the recorded values.")
+		usagePrintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	opts.args = fs.Args()
+
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("--format must be text or json, got %q", *format)
+	}
+	writeOpts, err := opts.writeOptions()
+	if err != nil {
+		return err
+	}
+
+	m, err := loadManifest()
+	hasManifest := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if !opts.hasIntent() && hasManifest {
+		if m.Intent == "" {
+			return errNoRecordedIntent("pass the intent with --intent-file FILE")
+		}
+		logVerbose("checking against the intent recorded in %s", manifestPath())
+		opts = overlayFlags(m.generateOptions(), fs, opts)
+	}
+	opts.check = true
+	// Check never calls a model: refined outputs are compared with the hashes
+	// recorded when they were written instead.
+	refined := opts.refine || opts.cli != ""
+	opts.refine, opts.cli = false, ""
+	if refined && !hasManifest {
+		return fmt.Errorf("refined outputs can only be checked against %s; generate them once to record it", manifestPath())
+	}
+	if opts.withContext {
+		logWarning("--context lists the latest commits, so outputs that use {{background}} show as stale after every new commit")
+	}
+
+	rendered, err := renderOutputs(configDir, opts)
+	if err != nil {
+		return err
+	}
+
+	report := checkReport{OK: true}
+	for _, out := range rendered.outputs {
+		policy := writeOpts.policyFor(out.packOutput)
+		var result checkResult
+		if refined {
+			if result, err = checkRecorded(m, out, policy); err != nil {
+				return err
+			}
+		} else {
+			plan, err := planOutput(out.File, out.content, policy)
+			if err != nil {
+				return err
+			}
+			result = checkPlan(plan)
+			if recorded, ok := m.output(out.File); ok && result.Status != checkOK {
+				result.ChangedInputs = changedInputs(recorded.Inputs, out.inputs)
+			}
+		}
+		report.OK = report.OK && result.Status == checkOK
+		report.Outputs = append(report.Outputs, result)
+	}
+
+	if *format == "json" {
+		if err := writeCheckJSON(os.Stdout, report); err != nil {
+			return err
+		}
+	} else if err := writeCheckText(os.Stdout, report); err != nil {
+		return err
+	}
+
+	if stale := report.staleCount(); stale > 0 {
+		return fmt.Errorf("%d of %d outputs out of date; regenerate them with the same intent or beet regenerate (beet diff shows the changes)", stale, len(report.Outputs))
+	}
+	return nil
+}
+
+// overlayFlags applies the flags set on the command line over the options
+// replayed from the manifest. --set adds to the recorded values, and the cache
+// flags always come from the command line.
+func overlayFlags(replayed *generateOptions, fs *flag.FlagSet, explicit *generateOptions) *generateOptions {
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "t", "template":
+			replayed.template = explicit.template
+		case "p", "pack":
+			replayed.pack = explicit.pack
+		case "vars":
+			replayed.varsFile = explicit.varsFile
+		case "set":
+			replayed.set = append(slices.Clone(replayed.set), explicit.set...)
+		case "context":
+			replayed.withContext = explicit.withContext
+		case "context-budget":
+			replayed.contextBudget = explicit.contextBudget
+		case "refine":
+			replayed.refine = explicit.refine
+		case "cli":
+			replayed.cli = explicit.cli
+		}
+	})
+	replayed.noCache, replayed.cacheTTL = explicit.noCache, explicit.cacheTTL
+	return replayed
+}
+
+func checkPlan(plan outputPlan) checkResult {
+	result := checkResult{File: plan.path, Status: checkOK}
+	switch {
+	case !plan.changed():
+	case !plan.exists:
+		result.Status = checkMissing
+	default:
+		result.Status = checkStale
+	}
+	if result.Status != checkOK {
+		result.Added, result.Removed = countChanges(diffLines(splitDiffLines(plan.old), splitDiffLines(plan.content)))
+	}
+	return result
+}
+
+// checkRecorded checks a refined output without refining it again: the file
+// must still match the hash in the manifest, and so must each of its inputs.
+func checkRecorded(m manifest, out renderedOutput, policy onExistsPolicy) (checkResult, error) {
+	result := checkResult{File: out.File, Status: checkOK, Refined: true}
+	data, err := os.ReadFile(out.File)
+	if errors.Is(err, os.ErrNotExist) {
+		result.Status = checkMissing
+		return result, nil
+	}
+	if err != nil {
+		return checkResult{}, fmt.Errorf("read %s: %w", out.File, err)
+	}
+	if policy == onExistsSkip {
+		return result, nil
+	}
+	recorded, ok := m.output(out.File)
+	if !ok {
+		result.Status = checkStale
+		return result, nil
+	}
+	result.ChangedInputs = changedInputs(recorded.Inputs, out.inputs)
+	if len(result.ChangedInputs) > 0 || sha256Hex(string(data)) != recorded.SHA256 {
+		result.Status = checkStale
+	}
+	return result, nil
+}
+
+func (r checkReport) staleCount() int {
+	n := 0
+	for _, out := range r.Outputs {
+		if out.Status != checkOK {
+			n++
+		}
+	}
+	return n
+}
+
+func writeCheckJSON(w io.Writer, report checkReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("encode check report: %w", err)
+	}
+	return nil
+}
+
+func writeCheckText(w io.Writer, report checkReport) error {
+	for _, out := range report.Outputs {
+		line := fmt.Sprintf("%-8s %s", out.Status, out.File)
+		switch {
+		case out.Status == checkStale && out.Refined:
+			line += " (refined)"
+		case out.Status == checkStale:
+			line += fmt.Sprintf(" (+%d -%d)", out.Added, out.Removed)
+		}
+		if len(out.ChangedInputs) > 0 {
+			line += "; changed: " + strings.Join(out.ChangedInputs, ", ")
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleCheckDetectsDrift(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/rules.md", "# Rules\n<!-- beet:begin guidelines -->\n{{guidelines}}\n<!-- beet:end guidelines -->\n")
+	writeConfigFile(t, configDir, "guidelines/style.md", "Keep it short.\n")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: RULES.md\n    template: rules.md\n")
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("INTENT.md", []byte("Ship it\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"-p", "p", "--intent-file", "INTENT.md"}
+
+	if err := handleCheck(configDir, args); err == nil || !strings.Contains(err.Error(), "1 of 1 outputs out of date") {
+		t.Fatalf("missing output: got %v", err)
+	}
+	if err := handleGenerate(configDir, args); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if err := os.Remove(filepath.Join(configDir, lastIntentFilename)); err != nil {
+		t.Fatal(err)
+	}
+	if err := handleCheck(configDir, args); err != nil {
+		t.Fatalf("fresh outputs: %v", err)
+	}
+
+	// Hand edits outside managed regions are not drift.
+	appendFile(t, "RULES.md", "My notes.\n")
+	if err := handleCheck(configDir, args); err != nil {
+		t.Fatalf("edit outside regions: %v", err)
+	}
+
+	writeConfigFile(t, configDir, "guidelines/style.md", "Keep it shorter.\n")
+	if err := handleCheck(configDir, args); err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Fatalf("changed guideline: got %v", err)
+	}
+	if b, _ := os.ReadFile("RULES.md"); strings.Contains(string(b), "shorter") {
+		t.Fatal("check wrote RULES.md")
+	}
+	if _, err := os.Stat(filepath.Join(configDir, lastIntentFilename)); err == nil {
+		t.Fatal("check saved the last intent")
+	}
+}
+
+func TestHandleCheckFailPolicy(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "v1 {{intent}}\n")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: OUT.md\n    template: t.md\n    on_exists: fail\n")
+	t.Chdir(t.TempDir())
+	args := []string{"-p", "p", "ship"}
+
+	if err := handleGenerate(configDir, args); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if err := handleCheck(configDir, args); err != nil {
+		t.Fatalf("unchanged output: %v", err)
+	}
+
+	writeConfigFile(t, configDir, "templates/t.md", "v2 {{intent}}\n")
+	if err := handleCheck(configDir, args); err == nil || !strings.Contains(err.Error(), "1 of 1 outputs out of date") {
+		t.Fatalf("changed template: got %v", err)
+	}
+	if err := handleDiff(configDir, append([]string{"--stat"}, args...)); err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if err := handleGenerate(configDir, args); err == nil || !strings.Contains(err.Error(), "already exists (on_exists: fail)") {
+		t.Fatalf("generate over a fail output: got %v", err)
+	}
+}
+
+func TestHandleCheckOverlaysFlagsOnManifest(t *testing.T) {
+	configDir := setupManifestPack(t)
+	writeConfigFile(t, configDir, "packs/other.yaml", "outputs:\n  - file: OTHER.md\n    template: rules.md\n")
+	if err := handleGenerate(configDir, []string{"-p", "p", "--set", "team=core", "Ship it"}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	if err := handleCheck(configDir, []string{"--set", "team=core"}); err != nil {
+		t.Fatalf("same --set: %v", err)
+	}
+	if err := handleCheck(configDir, []string{"--set", "team=web"}); err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Fatalf("--set over the manifest: got %v", err)
+	}
+	if err := handleCheck(configDir, []string{"-p", "other"}); err == nil || !strings.Contains(err.Error(), "1 of 1 outputs") {
+		t.Fatalf("-p over the manifest: got %v", err)
+	}
+}
+
+func TestHandleCheckWarnsAboutContext(t *testing.T) {
+	configDir := setupManifestPack(t)
+	if err := handleGenerate(configDir, []string{"-p", "p", "--context", "--set", "team=core", "Ship it"}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	warnings := captureWarnings(t)
+	if err := handleCheck(configDir, nil); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if !strings.Contains(warnings.String(), "stale after every new commit") {
+		t.Fatalf("warnings = %q", warnings.String())
+	}
+}
+
+func TestHandleCheckComparesRefinedOutputsWithManifest(t *testing.T) {
+	configDir := setupManifestPack(t)
+	if err := handleGenerate(configDir, []string{"-p", "p", "--cli", echoBackendName, "--set", "team=core", "Ship it"}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	// Stand in for a model whose answer differs from the raw render.
+	if err := os.WriteFile("RULES.md", []byte("Refined rules.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := loadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Outputs[0].SHA256 = sha256Hex("Refined rules.\n")
+	if err := writeManifest(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handleCheck(configDir, nil); err != nil {
+		t.Fatalf("refined output as recorded: %v", err)
+	}
+	appendFile(t, "RULES.md", "Hand edit.\n")
+	if err := handleCheck(configDir, nil); err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Fatalf("edited refined output: got %v", err)
+	}
+	if err := os.WriteFile("RULES.md", []byte("Refined rules.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeConfigFile(t, configDir, "guidelines/style.md", "Keep it shorter.\n")
+	if err := handleCheck(configDir, nil); err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Fatalf("changed guideline: got %v", err)
+	}
+}
+
+func TestHandleCheckRequiresIntent(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "{{intent}}\n")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: OUT.md\n    template: t.md\n")
+	t.Chdir(t.TempDir())
+
+	if err := handleCheck(configDir, []string{"-p", "p"}); err == nil || !strings.Contains(err.Error(), "--intent-file") {
+		t.Fatalf("got %v, want a missing intent error", err)
+	}
+	if err := handleCheck(configDir, []string{"-p", "p", "--format", "xml", "x"}); err == nil || !strings.Contains(err.Error(), "--format") {
+		t.Fatalf("got %v, want a format error", err)
+	}
+}
+
+func TestCheckReport(t *testing.T) {
+	report := checkReport{Outputs: []checkResult{
+		checkPlan(outputPlan{path: "A.md", exists: true, old: "a\n", content: "a\n", write: true}),
+		checkPlan(outputPlan{path: "B.md", content: "b\n", write: true}),
+		checkPlan(outputPlan{path: "C.md", exists: true, old: "old\n", content: "new\nmore\n", write: true}),
+		checkPlan(outputPlan{path: "D.md", exists: true, old: "kept\n", content: "kept\n"}),
+		{File: "E.md", Status: checkStale, Refined: true, ChangedInputs: []string{"guideline style"}},
+	}}
+	if got := report.staleCount(); got != 3 {
+		t.Fatalf("staleCount = %d, want 3", got)
+	}
+
+	var text bytes.Buffer
+	if err := writeCheckText(&text, report); err != nil {
+		t.Fatalf("writeCheckText: %v", err)
+	}
+	want := "ok       A.md\nmissing  B.md\nstale    C.md (+2 -1)\nok       D.md\nstale    E.md (refined); changed: guideline style\n"
+	if text.String() != want {
+		t.Fatalf("text report:\n%s\nwant:\n%s", text.String(), want)
+	}
+
+	var out bytes.Buffer
+	if err := writeCheckJSON(&out, report); err != nil {
+		t.Fatalf("writeCheckJSON: %v", err)
+	}
+	var decoded checkReport
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if decoded.Outputs[1].Status != checkMissing || decoded.Outputs[2].Added != 2 || decoded.Outputs[2].Removed != 1 {
+		t.Fatalf("decoded report: %+v", decoded)
+	}
+}
+
+func appendFile(t *testing.T, path, text string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -40,21 +40,29 @@ const (
   COMPREPLY=()
   cur="${COMP_WORDS[COMP_CWORD]}"
   prev="${COMP_WORDS[COMP_CWORD-1]}"
-  local commands="templates packs doctor pack template config completion"
-  local global_opts="--help --dry-run --force-agents -t --template -p --pack"
+  local commands="init templates packs guidelines diff check regenerate doctor pack template config cache completion"
+  local global_opts="--help --dry-run --force --no-clobber --refine --no-cache -t --template -p --pack"
   case "$prev" in
     pack)
-      COMPREPLY=( $(compgen -W "list init edit" -- "$cur") )
+      COMPREPLY=( $(compgen -W "list init edit show" -- "$cur") )
       return 0
       ;;
     template)
-      COMPREPLY=( $(compgen -W "new" -- "$cur") )
+      COMPREPLY=( $(compgen -W "new show" -- "$cur") )
       return 0
       ;;
     config)
       COMPREPLY=( $(compgen -W "restore" -- "$cur") )
       return 0
       ;;
+    cache)
+      COMPREPLY=( $(compgen -W "ls clear stats" -- "$cur") )
+      return 0
+      ;;
+    guidelines)
+      COMPREPLY=( $(compgen -W "list" -- "$cur") )
+      return 0
+      ;;
   esac
   COMPREPLY=( $(compgen -W "${commands} ${global_opts}" -- "$cur") )
 }
@@ -63,7 +71,7 @@ complete -F _beet_completions beet
 	zshCompletion = `#compdef beet
 
 _beet_commands() {
-  _values 'commands' templates packs doctor pack template config completion
+  _values 'commands' init templates packs guidelines diff check regenerate doctor pack template config cache completion
 }
 
 _beet() {
@@ -79,16 +87,22 @@ _beet() {
     args)
       case $words[1] in
         pack)
-          _values 'pack commands' list init edit
+          _values 'pack commands' list init edit show
           ;;
         template)
-          _values 'template commands' new
+          _values 'template commands' new show
           ;;
         config)
           _values 'config commands' restore
           ;;
+        cache)
+          _values 'cache commands' ls clear stats
+          ;;
+        guidelines)
+          _values 'guidelines commands' list
+          ;;
         *)
-          _values 'options' --help --dry-run --force-agents -t --template -p --pack
+          _values 'options' --help --dry-run --force --no-clobber --refine --no-cache -t --template -p --pack
           ;;
       esac
       ;;
@@ -140,7 +154,7 @@ func main() {
 			fmt.Println(name)
 		}
 	case "doctor":
-		if err := runDoctor(os.Stdout); err != nil {
+		if err := runDoctor(os.Stdout, configDir); err != nil {
 			log.Fatalf("doctor: %v", err)
 		}
 	case "pack":
@@ -155,6 +169,30 @@ func main() {
 		if err := handleConfig(configDir, args[1:]); err != nil {
 			log.Fatalf("config: %v", err)
 		}
+	case "init":
+		if err := handleInit(configDir, args[1:]); err != nil {
+			log.Fatalf("init: %v", err)
+		}
+	case "guidelines":
+		if err := handleGuidelinesCommand(configDir, args[1:]); err != nil {
+			log.Fatalf("guidelines: %v", err)
+		}
+	case "diff":
+		if err := handleDiff(configDir, args[1:]); err != nil {
+			log.Fatalf("diff: %v", err)
+		}
+	case "check":
+		if err := handleCheck(configDir, args[1:]); err != nil {
+			log.Fatalf("check: %v", err)
+		}
+	case "regenerate":
+		if err := handleRegenerate(configDir, args[1:]); err != nil {
+			log.Fatalf("regenerate: %v", err)
+		}
+	case "cache":
+		if err := handleCacheCommand(configDir, args[1:]); err != nil {
+			log.Fatalf("cache: %v", err)
+		}
 	case "completion":
 		if err := handleCompletion(args[1:]); err != nil {
 			log.Fatalf("completion: %v", err)
@@ -203,7 +241,7 @@ func handleCompletion(args []string) error {
 
 func handlePackCommand(configDir string, args []string) error {
 	if len(args) == 0 {
-		return fmt.Errorf("usage: beet pack [list|init|edit]")
+		return fmt.Errorf("usage: beet pack [list|init|edit|show]")
 	}
 
 	switch args[0] {
@@ -246,19 +284,33 @@ func handlePackCommand(configDir string, args []string) error {
 			return fmt.Errorf("usage: beet pack edit <name>")
 		}
 		filename := normalizePackName(args[1])
-		path := filepath.Join(configDir, packsDirName, filename)
+		path := packPath(configDir, filename)
 		if _, err := os.Stat(path); err != nil {
 			return fmt.Errorf("pack %s not found: %w", filename, err)
 		}
 		return openForEdit(path)
+	case "show":
+		fs := flag.NewFlagSet("pack show", flag.ContinueOnError)
+		fs.SetOutput(os.Stdout)
+		resolved := fs.Bool("resolved", false, "print the pack with inherited outputs flattened")
+		if err := fs.Parse(args[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return err
+		}
+		if len(fs.Args()) == 0 {
+			return fmt.Errorf("usage: beet pack show [--resolved] <name>")
+		}
+		return showPack(os.Stdout, configDir, fs.Args()[0], *resolved)
 	default:
-		return fmt.Errorf("usage: beet pack [list|init|edit]")
+		return fmt.Errorf("usage: beet pack [list|init|edit|show]")
 	}
 }
 
 func handleTemplateCommand(configDir string, args []string) error {
 	if len(args) == 0 {
-		return fmt.Errorf("usage: beet template new <name>")
+		return fmt.Errorf("usage: beet template [new|show] <name>")
 	}
 
 	switch args[0] {
@@ -290,8 +342,36 @@ func handleTemplateCommand(configDir string, args []string) error {
 			return fmt.Errorf("write template: %w", err)
 		}
 		return nil
+	case "show":
+		fs := flag.NewFlagSet("template show", flag.ContinueOnError)
+		fs.SetOutput(os.Stdout)
+		expanded := fs.Bool("expanded", false, "resolve partial includes")
+		if err := fs.Parse(args[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return err
+		}
+		if len(fs.Args()) == 0 {
+			return fmt.Errorf("usage: beet template show [--expanded] <name>")
+		}
+		templateName := fs.Args()[0]
+		content, err := loadTemplate(configDir, templateName)
+		if err != nil {
+			return err
+		}
+		if *expanded {
+			content, err = expandTemplate(normalizeTemplateName(templateName), content, partialsFrom(configDir))
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprint(os.Stdout, content); err != nil {
+			return err
+		}
+		return nil
 	default:
-		return fmt.Errorf("usage: beet template new <name>")
+		return fmt.Errorf("usage: beet template [new|show] <name>")
 	}
 }
 
@@ -308,66 +388,283 @@ func openForEdit(path string) error {
 	if err := defaultBrowser.OpenFile(path); err != nil {
 		return fmt.Errorf("open default app: %w", err)
 	}
-	if err := waitForContentFn(path); err != nil {
+	if err := waitForContentFn(path, ""); err != nil {
 		return fmt.Errorf("await default edit: %w", err)
 	}
 	return nil
 }
 
-func handleGenerate(configDir string, args []string) error {
-	fs := flag.NewFlagSet("beet", flag.ContinueOnError)
+// generateOptions are the flags shared by generation and the commands that
+// preview it.
+type generateOptions struct {
+	template       string
+	pack           string
+	dryRun         bool
+	force          bool
+	noClobber      bool
+	forceAgents    bool
+	refine         bool
+	cli            string
+	recordFixtures bool
+	noCache        bool
+	cacheTTL       string
+	withContext    bool
+	contextBudget  int
+	intentSources  []intentSource
+	intentFrom     string
+	fromDiff       diffBaseFlag
+	diffBudget     int
+	varsFile       string
+	set            stringList
+	args           []string
+	// check renders for beet check: the intent must come from the command
+	// line or the manifest.
+	check bool
+}
+
+func newGenerateFlags(name string) (*flag.FlagSet, *generateOptions) {
+	o := &generateOptions{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
 	fs.SetOutput(os.Stdout)
+	fs.StringVar(&o.template, "t", "", "template name")
+	fs.StringVar(&o.template, "template", "", "template name")
+	fs.StringVar(&o.pack, "p", "", "pack name")
+	fs.StringVar(&o.pack, "pack", "", "pack name")
+	fs.BoolVar(&o.force, "force", false, "overwrite existing outputs regardless of their on_exists policy")
+	fs.BoolVar(&o.noClobber, "no-clobber", false, "never modify existing outputs")
+	fs.BoolVar(&o.forceAgents, "force-agents", false, "deprecated: overwrite an existing agents.md")
+	fs.BoolVar(&o.refine, "refine", false, "pipe each rendered prompt through the detected CLI")
+	fs.StringVar(&o.cli, "cli", "", "refine with this adapter or built-in backend (echo, fixture); implies --refine")
+	fs.BoolVar(&o.recordFixtures, "record-fixtures", false, "save refinement responses as fixtures for the fixture backend")
+	fs.BoolVar(&o.noCache, "no-cache", false, "always call the CLI instead of reusing cached refinements")
+	fs.StringVar(&o.cacheTTL, "cache-ttl", "", "reuse cached refinements younger than this (default $"+envCacheTTL+" or 168h)")
+	fs.BoolVar(&o.withContext, "context", false, "fill {{background}} with a summary of the repository")
+	fs.IntVar(&o.contextBudget, "context-budget", defaultContextBudget, "maximum bytes of repository context")
+	fs.Var(intentSourceFlag{sources: &o.intentSources}, "intent", "intent text, or - to read stdin (repeatable)")
+	fs.Var(intentSourceFlag{sources: &o.intentSources, file: true}, "intent-file", "read intent from a file (repeatable)")
+	fs.StringVar(&o.intentFrom, "intent-from", "", "import the intent from a GitHub issue JSON or Jira XML/CSV export")
+	fs.Var(&o.fromDiff, "from-diff", "add the branch diff against a base ref (default origin/HEAD, main or master) to the intent")
+	fs.Var(diffBaseRefFlag{flag: &o.fromDiff}, "diff-base", "base `ref` for --from-diff; implies --from-diff")
+	fs.IntVar(&o.diffBudget, "diff-budget", defaultDiffBudget, "maximum bytes of --from-diff context")
+	fs.StringVar(&o.varsFile, "vars", "", "YAML file of placeholder values")
+	fs.Var(&o.set, "set", "set a placeholder value (key=value, repeatable)")
+	return fs, o
+}
+
+func (o *generateOptions) writeOptions() (writeOptions, error) {
+	if o.forceAgents {
+		logWarning("--force-agents is deprecated; set on_exists: overwrite on agents.md in your pack, or use --force")
+	}
+	w := writeOptions{force: o.force, forceAgents: o.forceAgents, noClobber: o.noClobber}
+	if w.noClobber && (w.force || w.forceAgents) {
+		return writeOptions{}, fmt.Errorf("--no-clobber cannot be combined with --force or --force-agents")
+	}
+	return w, nil
+}
+
+func (o *generateOptions) hasIntent() bool {
+	return len(o.args) > 0 || len(o.intentSources) > 0 || o.intentFrom != "" || o.fromDiff.enabled
+}
+
+// intentRecordable reports whether the intent came only from text and files
+// on the command line. A ticket, diff, stdin or editor intent can hold more
+// than belongs in a committed manifest, so only its hash is recorded.
+func (o *generateOptions) intentRecordable() bool {
+	if !o.hasIntent() || o.intentFrom != "" || o.fromDiff.enabled {
+		return false
+	}
+	for _, src := range o.intentSources {
+		if !src.file && src.value == "-" {
+			return false
+		}
+	}
+	return true
+}
+
+func handleGenerate(configDir string, args []string) error {
+	fs, opts := newGenerateFlags("beet")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "render without writing files")
 	fs.Usage = func() {
 		usagePrintln(fs.Output(), "Usage: beet [flags] [intent|file]")
 		usagePrintln(fs.Output(), "\nFlags:")
 		fs.PrintDefaults()
-		usagePrintln(fs.Output(), "\nCommands: beet templates | beet packs | beet doctor | beet config restore | beet pack [list|init|edit] | beet template new | beet completion [--shell bash|zsh]")
+		usagePrintln(fs.Output(), "\nCommands: beet init | beet templates | beet packs | beet guidelines list | beet diff | beet check | beet regenerate | beet doctor | beet config restore | beet cache [ls|clear|stats] | beet pack [list|init|edit|show] | beet template [new|show] | beet completion [--shell bash|zsh]")
 		usagePrintln(fs.Output(), "Notes: packs are bootstrapped and selectable with -p/--pack (default, extended, comprehensive).")
 		usagePrintln(fs.Output(), "       generation renders all outputs defined by the pack; -t/--template only overrides WORK_PROMPT.md in the default pack.")
 	}
 
-	template := fs.String("t", "", "template name")
-	templateLong := fs.String("template", "", "template name")
-	pack := fs.String("p", "", "pack name")
-	packLong := fs.String("pack", "", "pack name")
-	dryRun := fs.Bool("dry-run", false, "render without writing files")
-	forceAgents := fs.Bool("force-agents", false, "overwrite agents.md")
-
 	if err := fs.Parse(args); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			return nil
 		}
 		return err
 	}
+	opts.args = fs.Args()
 
-	if err := requireConfigState(configDir); err != nil {
+	writeOpts, err := opts.writeOptions()
+	if err != nil {
+		return err
+	}
+	return generate(configDir, opts, writeOpts)
+}
+
+// generate renders and writes every output, then remembers the intent for
+// the next scaffold and records the run in the manifest.
+func generate(configDir string, opts *generateOptions, writeOpts writeOptions) error {
+	rendered, err := renderOutputs(configDir, opts)
+	if err != nil {
 		return err
 	}
 
-	intent, err := parseIntent(fs.Args())
+	m, err := newManifest(rendered, opts)
 	if err != nil {
 		return err
 	}
+	if opts.dryRun {
+		for _, out := range rendered.outputs {
+			fmt.Printf("=== %s ===\n%s\n", out.File, out.content)
+		}
+		return nil
+	}
+
+	// Plan every output before writing any, so an on_exists: fail conflict
+	// leaves the files and the manifest as they were.
+	plans := make([]outputPlan, len(rendered.outputs))
+	var conflicts []error
+	for i, out := range rendered.outputs {
+		if plans[i], err = planOutput(out.File, out.content, writeOpts.policyFor(out.packOutput)); err != nil {
+			return err
+		}
+		if plans[i].conflict {
+			conflicts = append(conflicts, plans[i].conflictError())
+		}
+	}
+	if len(conflicts) > 0 {
+		return errors.Join(conflicts...)
+	}
+
+	for i, out := range rendered.outputs {
+		if err := plans[i].apply(); err != nil {
+			return err
+		}
+		if plans[i].changed() {
+			logVerbose("wrote %s (%d bytes)", out.File, len(plans[i].content))
+		}
+		m.Outputs = append(m.Outputs, manifestOutput{File: out.File, SHA256: sha256Hex(plans[i].content), Inputs: out.inputs})
+	}
+	if err := saveLastIntent(configDir, rendered.intent); err != nil {
+		logWarning("%v", err)
+	}
+	return writeManifest(m)
+}
+
+// renderedOutput is a pack output with its final (possibly refined) text and
+// the inputs that produced it.
+type renderedOutput struct {
+	packOutput
+	content string
+	inputs  manifestInputs
+}
 
-	tmplName := firstNonEmpty(*template, *templateLong)
-	packName := firstNonEmpty(*pack, *packLong)
+// rendering is the result of resolving and rendering a pack.
+type rendering struct {
+	packName string
+	pack     pack
+	intent   string
+	outputs  []renderedOutput
+}
+
+// renderOutputs resolves the pack, intent and placeholders from opts and
+// renders every output without touching the files on disk.
+func renderOutputs(configDir string, opts *generateOptions) (rendering, error) {
+	if err := requireConfigState(configDir); err != nil {
+		return rendering{}, err
+	}
+
+	tmplName := opts.template
+	packName := opts.pack
 
 	if packName == "" {
-		packName = defaultPackName
+		settings, path, err := loadProjectSettings(configDir)
+		if err != nil {
+			return rendering{}, err
+		}
+		if settings.Pack != "" {
+			logVerbose("using pack %s pinned in %s", settings.Pack, path)
+		}
+		packName = firstNonEmpty(settings.Pack, defaultPackName)
 	}
 
-	logVerbose("generate params: pack=%s template=%q dry-run=%t force-agents=%t", packName, tmplName, *dryRun, *forceAgents)
+	logVerbose("generate params: pack=%s template=%q dry-run=%t force=%t force-agents=%t no-clobber=%t refine=%t", packName, tmplName, opts.dryRun, opts.force, opts.forceAgents, opts.noClobber, opts.refine)
 
 	p, err := loadPack(configDir, packName)
 	if err != nil {
-		return err
+		return rendering{}, err
+	}
+	if opts.check && !opts.hasIntent() {
+		return rendering{}, fmt.Errorf("no intent given; pass the committed intent with --intent-file FILE or generate once to record it in %s", manifestPath())
+	}
+
+	intent, err := parseIntent(opts.args, intentOptions{
+		scaffold: func() (string, error) {
+			return intentScaffold(configDir, packName, p, tmplName)
+		},
+		ticket:     opts.intentFrom,
+		sources:    opts.intentSources,
+		fromDiff:   opts.fromDiff,
+		diffBudget: opts.diffBudget,
+	})
+	if err != nil {
+		return rendering{}, err
 	}
 
 	guidelines, err := loadGuidelines(configDir)
 	if err != nil {
-		return err
+		return rendering{}, err
+	}
+	workdir, err := os.Getwd()
+	if err != nil {
+		return rendering{}, fmt.Errorf("getwd: %w", err)
+	}
+	facts := detectRepo(workdir)
+	applicable := applicableGuidelines(guidelines, workdir, facts.languages)
+
+	inputs, err := resolvePlaceholderInputs(intent, opts.varsFile, opts.set)
+	if err != nil {
+		return rendering{}, err
+	}
+	inputs.repo = facts.placeholders()
+	if opts.withContext {
+		var outputs []string
+		for _, out := range p.Outputs {
+			outputs = append(outputs, out.File)
+		}
+		background, err := collectRepoContext(workdir, opts.contextBudget, outputs)
+		if err != nil {
+			return rendering{}, err
+		}
+		logVerbose("collected %d bytes of repository context", len(background))
+		inputs.repo["background"] = background
 	}
 
+	var r *refiner
+	if opts.refine || opts.cli != "" {
+		ttl, err := cacheTTL(opts.cacheTTL)
+		if err != nil {
+			return rendering{}, err
+		}
+		r, err = newRefiner(configDir, backendOptions{
+			cli:      strings.TrimSpace(opts.cli),
+			record:   opts.recordFixtures,
+			noCache:  opts.noCache,
+			cacheTTL: ttl,
+		})
+		if err != nil {
+			return rendering{}, err
+		}
+	}
+
+	var rendered []renderedOutput
 	for _, out := range p.Outputs {
 		templateName := out.Template
 		if tmplName != "" && strings.EqualFold(out.File, workPromptFilename) {
@@ -376,27 +673,79 @@ func handleGenerate(configDir string, args []string) error {
 
 		templateContent, err := loadTemplate(configDir, templateName)
 		if err != nil {
-			return err
+			return rendering{}, err
 		}
 
-		normalized := normalizeTemplateName(templateName)
-		label := strings.TrimSuffix(normalized, filepath.Ext(normalized))
-		prompt := buildWorkPrompt(label, templateContent, guidelines, intent)
+		// Names are checked against every guideline so a pack can list one
+		// that doesn't apply to this repository.
+		selected, err := out.Guidelines.selectFrom(guidelines)
+		if err != nil {
+			return rendering{}, fmt.Errorf("%s: %w", out.File, err)
+		}
+		selected = keepGuidelines(selected, applicable)
 
-		logVerbose("rendering %s via template %s", out.File, label)
+		used := manifestInputs{}
+		used.add("template", normalizeTemplateName(templateName), templateContent)
+		for _, g := range selected {
+			used.add("guideline", g.name, g.content)
+		}
+		values := inputs.forOutput(p, out, formatGuidelines(selected))
+		prompt, empty, err := buildPrompt(templateName, templateContent, values, used.recordPartials(partialsFrom(configDir)))
+		if err != nil {
+			return rendering{}, err
+		}
+		// Guideline text is already recorded per guideline.
+		used.add("placeholders", "", values.canonical("guidelines"))
 
-		if *dryRun {
-			fmt.Printf("=== %s ===\n%s\n", out.File, prompt)
-			continue
+		logVerbose("rendering %s via template %s", out.File, normalizeTemplateName(templateName))
+		if len(empty) > 0 {
+			logWarning("%s: empty placeholders: %s", out.File, strings.Join(empty, ", "))
 		}
+		content, err := r.refine(out.File, prompt)
+		if err != nil {
+			return rendering{}, err
+		}
+		rendered = append(rendered, renderedOutput{packOutput: out, content: content, inputs: used})
+	}
 
-		if err := writeRenderedOutput(out.File, prompt, *forceAgents); err != nil {
-			return err
+	return rendering{packName: packName, pack: p, intent: intent, outputs: rendered}, nil
+}
+
+// placeholderInputs separates the values every output shares from those the
+// user supplied, so pack and output vars can sit between the two.
+type placeholderInputs struct {
+	intent string
+	repo   placeholderValues
+	user   placeholderValues
+}
+
+func resolvePlaceholderInputs(intent, varsFile string, assignments []string) (placeholderInputs, error) {
+	doc, err := parseIntentDocument(intent)
+	if err != nil {
+		return placeholderInputs{}, err
+	}
+	user := intentPlaceholders(doc)
+
+	if varsFile != "" {
+		fileValues, err := loadVarsFile(varsFile)
+		if err != nil {
+			return placeholderInputs{}, err
 		}
-		logVerbose("wrote %s (%d bytes)", out.File, len(prompt))
+		user = user.merge(fileValues)
 	}
 
-	return nil
+	setValues, err := parseSetValues(assignments)
+	if err != nil {
+		return placeholderInputs{}, err
+	}
+	return placeholderInputs{
+		intent: intent,
+		user:   user.merge(setValues),
+	}, nil
+}
+
+func (in placeholderInputs) forOutput(p pack, out packOutput, guidelineText string) placeholderValues {
+	return basePlaceholders(in.intent, guidelineText).merge(in.repo).merge(p.Vars).merge(out.Vars).merge(in.user)
 }
 
 func usagePrintln(w io.Writer, line string) {
@@ -405,42 +754,194 @@ func usagePrintln(w io.Writer, line string) {
 	}
 }
 
-func parseIntent(remaining []string) (string, error) {
-	if len(remaining) > 0 {
-		if len(remaining) == 1 {
-			if info, err := os.Stat(remaining[0]); err == nil && !info.IsDir() {
-				b, err := os.ReadFile(remaining[0])
-				if err != nil {
-					return "", fmt.Errorf("read intent file: %w", err)
-				}
-				intent := strings.TrimSpace(string(b))
-				if intent == "" {
-					return "", fmt.Errorf("intent is empty; provide input")
-				}
-				return intent, nil
+// intentOptions are the intent sources beyond positional args, stdin and the
+// editor.
+type intentOptions struct {
+	scaffold   func() (string, error)
+	ticket     string
+	sources    []intentSource
+	fromDiff   diffBaseFlag
+	diffBudget int
+}
+
+// intentSource is one --intent or --intent-file value; --intent - reads stdin.
+type intentSource struct {
+	file  bool
+	value string
+}
+
+// intentSourceFlag appends to a list shared by --intent and --intent-file so
+// the sources keep their command-line order.
+type intentSourceFlag struct {
+	sources *[]intentSource
+	file    bool
+}
+
+func (f intentSourceFlag) String() string {
+	if f.sources == nil {
+		return ""
+	}
+	var values []string
+	for _, src := range *f.sources {
+		if src.file == f.file {
+			values = append(values, src.value)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+func (f intentSourceFlag) Set(value string) error {
+	*f.sources = append(*f.sources, intentSource{file: f.file, value: value})
+	return nil
+}
+
+// parseIntent combines, separated by blank lines: the --intent-from ticket,
+// --intent and --intent-file values in command-line order, then positional
+// args, then the --from-diff context. With none of those it falls back to
+// piped stdin and the editor, seeded from opts.scaffold when set. Front
+// matter from any of the sources applies; see joinIntentParts.
+func parseIntent(remaining []string, opts intentOptions) (string, error) {
+	stdinSources := 0
+	for _, src := range opts.sources {
+		if !src.file && src.value == "-" {
+			stdinSources++
+		}
+	}
+	if stdinSources > 1 {
+		return "", fmt.Errorf("--intent - given more than once; stdin can only be read once")
+	}
+
+	var parts []string
+	if opts.ticket != "" {
+		t, err := loadTicket(opts.ticket)
+		if err != nil {
+			return "", err
+		}
+		text, err := t.intentText()
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, text)
+	}
+	for _, src := range opts.sources {
+		var (
+			part string
+			err  error
+		)
+		switch {
+		case src.file:
+			part, err = readIntentFile(src.value)
+		case src.value == "-":
+			if part, err = readStdinIntent(); err == nil && part == "" {
+				err = fmt.Errorf("intent from stdin is empty")
+			}
+		default:
+			part = strings.TrimSpace(src.value)
+			if part == "" {
+				err = fmt.Errorf("--intent is empty")
 			}
 		}
-		intent := strings.TrimSpace(strings.Join(remaining, " "))
-		if intent == "" {
-			return "", fmt.Errorf("intent is empty; provide input")
+		if err != nil {
+			return "", err
 		}
-		return intent, nil
+		parts = append(parts, part)
+	}
+	if len(remaining) > 0 {
+		part, err := intentFromArgs(remaining)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	if opts.fromDiff.enabled {
+		wip, err := intentFromDiff(opts, remaining)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, wip)
+	}
+	if len(parts) > 0 {
+		return joinIntentParts(parts)
 	}
 
 	info, err := os.Stdin.Stat()
 	if err == nil && (info.Mode()&os.ModeCharDevice) == 0 {
-		b, err := io.ReadAll(os.Stdin)
+		intent, err := readStdinIntent()
 		if err != nil {
-			return "", fmt.Errorf("read stdin: %w", err)
+			return "", err
 		}
-		intent := strings.TrimSpace(string(b))
 		if intent == "" {
 			return "", fmt.Errorf("intent is empty; provide input")
 		}
 		return intent, nil
 	}
 
-	return intentFromEditor()
+	scaffold := ""
+	if opts.scaffold != nil {
+		if scaffold, err = opts.scaffold(); err != nil {
+			return "", err
+		}
+	}
+	return intentFromEditor(scaffold)
+}
+
+func readStdinIntent() (string, error) {
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("read stdin: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func readIntentFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read intent file: %w", err)
+	}
+	intent := strings.TrimSpace(string(b))
+	if intent == "" {
+		return "", fmt.Errorf("intent file %s is empty", path)
+	}
+	return intent, nil
+}
+
+func intentFromArgs(remaining []string) (string, error) {
+	if len(remaining) == 1 {
+		if info, err := os.Stat(remaining[0]); err == nil && !info.IsDir() {
+			b, err := os.ReadFile(remaining[0])
+			if err != nil {
+				return "", fmt.Errorf("read intent file: %w", err)
+			}
+			intent := strings.TrimSpace(string(b))
+			if intent == "" {
+				return "", fmt.Errorf("intent is empty; provide input")
+			}
+			return intent, nil
+		}
+	}
+	intent := strings.TrimSpace(strings.Join(remaining, " "))
+	if intent == "" {
+		return "", fmt.Errorf("intent is empty; provide input")
+	}
+	return intent, nil
+}
+
+// intentFromDiff builds the --from-diff context. remaining are the positional
+// args, which only ever hold intent text; a lone one naming a git ref most
+// likely meant to be the base, so that gets a warning.
+func intentFromDiff(opts intentOptions, remaining []string) (string, error) {
+	budget := opts.diffBudget
+	if budget == 0 {
+		budget = defaultDiffBudget
+	}
+	workdir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getwd: %w", err)
+	}
+	if opts.fromDiff.base == "" && len(remaining) == 1 && isGitRef(workdir, remaining[0]) {
+		logWarning("%q is read as intent text, not as the diff base; use --diff-base %s to compare against it", remaining[0], remaining[0])
+	}
+	return diffIntent(workdir, opts.fromDiff.base, budget)
 }
 
 func firstNonEmpty(values ...string) string {
@@ -452,24 +953,19 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
-func intentFromEditor() (string, error) {
+func intentFromEditor(scaffold string) (string, error) {
 	editor := strings.TrimSpace(os.Getenv("EDITOR"))
 	if editor == "" {
-		return intentFromDefaultApp()
+		return intentFromDefaultApp(scaffold)
 	}
 
-	tmp, err := os.CreateTemp("", "beet-intent-*.md")
+	path, cleanup, err := intentBuffer(scaffold)
 	if err != nil {
-		return "", fmt.Errorf("create temp file: %w", err)
-	}
-	defer func() {
-		_ = os.Remove(tmp.Name())
-	}()
-	if err := tmp.Close(); err != nil {
-		return "", fmt.Errorf("close temp file: %w", err)
+		return "", err
 	}
+	defer cleanup()
 
-	cmd := exec.Command(editor, tmp.Name())
+	cmd := exec.Command(editor, path)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -478,62 +974,84 @@ func intentFromEditor() (string, error) {
 		return "", fmt.Errorf("launch editor: %w", err)
 	}
 
-	if err := waitForContentFn(tmp.Name()); err != nil {
+	if err := waitForContentFn(path, ""); err != nil {
 		return "", fmt.Errorf("await editor content: %w", err)
 	}
 
-	b, err := os.ReadFile(tmp.Name())
+	b, err := os.ReadFile(path)
 	if err != nil {
 		return "", fmt.Errorf("read editor output: %w", err)
 	}
 
-	intent := strings.TrimSpace(string(b))
-	if intent == "" {
-		return "", fmt.Errorf("intent is empty; provide input")
-	}
-
-	return intent, nil
+	return editedIntent(string(b), scaffold)
 }
 
-func intentFromDefaultApp() (string, error) {
-	tmp, err := os.CreateTemp("", "beet-intent-*.md")
+func intentFromDefaultApp(scaffold string) (string, error) {
+	path, cleanup, err := intentBuffer(scaffold)
 	if err != nil {
-		return "", fmt.Errorf("create temp file: %w", err)
-	}
-	defer func() {
-		_ = os.Remove(tmp.Name())
-	}()
-	if err := tmp.Close(); err != nil {
-		return "", fmt.Errorf("close temp file: %w", err)
+		return "", err
 	}
+	defer cleanup()
 
-	if err := defaultBrowser.OpenFile(tmp.Name()); err != nil {
+	if err := defaultBrowser.OpenFile(path); err != nil {
 		return "", fmt.Errorf("open default app: %w", err)
 	}
 
-	if err := waitForContentFn(tmp.Name()); err != nil {
+	// The app returns immediately, so wait until the buffer no longer holds
+	// just the scaffold.
+	if err := waitForContentFn(path, scaffold); err != nil {
 		return "", fmt.Errorf("await default edit: %w", err)
 	}
 
-	b, err := os.ReadFile(tmp.Name())
+	b, err := os.ReadFile(path)
 	if err != nil {
 		return "", fmt.Errorf("read intent: %w", err)
 	}
 
-	intent := strings.TrimSpace(string(b))
+	return editedIntent(string(b), scaffold)
+}
+
+func intentBuffer(scaffold string) (string, func(), error) {
+	tmp, err := os.CreateTemp("", "beet-intent-*.md")
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp file: %w", err)
+	}
+	cleanup := func() {
+		_ = os.Remove(tmp.Name())
+	}
+	if _, err := tmp.WriteString(scaffold); err != nil {
+		_ = tmp.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("write scaffold: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("close temp file: %w", err)
+	}
+	return tmp.Name(), cleanup, nil
+}
+
+func editedIntent(text, scaffold string) (string, error) {
+	intent := strings.TrimSpace(text)
+	if scaffold != "" {
+		intent = stripScaffold(text)
+	}
 	if intent == "" {
 		return "", fmt.Errorf("intent is empty; provide input")
 	}
-
 	return intent, nil
 }
 
-func defaultWaitForContent(path string) error {
+// defaultWaitForContent polls path until it holds something other than
+// initial (ignoring surrounding whitespace).
+func defaultWaitForContent(path, initial string) error {
 	deadline := time.Now().Add(waitForContentTimeout)
 	for {
 		data, err := os.ReadFile(path)
-		if err == nil && strings.TrimSpace(string(data)) != "" {
-			return nil
+		if err == nil {
+			if content := strings.TrimSpace(string(data)); content != "" && content != strings.TrimSpace(initial) {
+				return nil
+			}
 		}
 		if time.Now().After(deadline) {
 			return fmt.Errorf("no content written to %s", path)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"io"
 	"os"
 	"path/filepath"
@@ -66,7 +67,7 @@ func TestParseIntentFromStdin(t *testing.T) {
 		t.Fatalf("close pipe writer: %v", err)
 	}
 
-	intent, err := parseIntent(nil)
+	intent, err := parseIntent(nil, intentOptions{})
 	if err != nil {
 		t.Fatalf("parseIntent returned error: %v", err)
 	}
@@ -81,7 +82,7 @@ func TestParseIntentFromFile(t *testing.T) {
 		t.Fatalf("write temp file: %v", err)
 	}
 
-	intent, err := parseIntent([]string{tmpFile})
+	intent, err := parseIntent([]string{tmpFile}, intentOptions{})
 	if err != nil {
 		t.Fatalf("parseIntent returned error: %v", err)
 	}
@@ -106,7 +107,7 @@ func TestParseIntentFromEditor(t *testing.T) {
 	os.Stdin = devNull
 	defer func() { os.Stdin = origStdin }()
 
-	intent, err := parseIntent(nil)
+	intent, err := parseIntent(nil, intentOptions{})
 	if err != nil {
 		t.Fatalf("parseIntent returned error: %v", err)
 	}
@@ -126,10 +127,10 @@ func TestIntentFromDefaultAppFallback(t *testing.T) {
 	defaultBrowser = browserFake(func(path string) error {
 		return os.WriteFile(path, []byte("from default app"), 0o644)
 	})
-	waitForContentFn = func(string) error { return nil }
+	waitForContentFn = func(string, string) error { return nil }
 	t.Setenv("EDITOR", "")
 
-	intent, err := intentFromEditor()
+	intent, err := intentFromEditor("")
 	if err != nil {
 		t.Fatalf("intentFromEditor returned error: %v", err)
 	}
@@ -157,7 +158,7 @@ func TestIntentFromEditorWaitsForContent(t *testing.T) {
 
 	origWait := waitForContentFn
 	waitCalled := false
-	waitForContentFn = func(path string) error {
+	waitForContentFn = func(path, _ string) error {
 		waitCalled = true
 		return os.WriteFile(path, []byte("from wait"), 0o644)
 	}
@@ -165,7 +166,7 @@ func TestIntentFromEditorWaitsForContent(t *testing.T) {
 		waitForContentFn = origWait
 	})
 
-	intent, err := parseIntent(nil)
+	intent, err := parseIntent(nil, intentOptions{})
 	if err != nil {
 		t.Fatalf("parseIntent returned error: %v", err)
 	}
@@ -283,7 +284,7 @@ func TestDefaultWaitForContentAcceptsNonWhitespace(t *testing.T) {
 		_ = os.WriteFile(tmpFile, []byte("content"), 0o644)
 	}()
 
-	if err := defaultWaitForContent(tmpFile); err != nil {
+	if err := defaultWaitForContent(tmpFile, ""); err != nil {
 		t.Fatalf("defaultWaitForContent returned error: %v", err)
 	}
 }
@@ -303,7 +304,7 @@ func TestDefaultWaitForContentRejectsWhitespace(t *testing.T) {
 		t.Fatalf("write temp file: %v", err)
 	}
 
-	if err := defaultWaitForContent(tmpFile); err == nil {
+	if err := defaultWaitForContent(tmpFile, ""); err == nil {
 		t.Fatalf("defaultWaitForContent should error on whitespace-only content")
 	}
 }
@@ -314,13 +315,13 @@ func TestParseIntentRejectsEmptyFile(t *testing.T) {
 		t.Fatalf("write temp file: %v", err)
 	}
 
-	if _, err := parseIntent([]string{tmpFile}); err == nil {
+	if _, err := parseIntent([]string{tmpFile}, intentOptions{}); err == nil {
 		t.Fatalf("parseIntent should error on empty file")
 	}
 }
 
 func TestParseIntentRejectsEmptyArgs(t *testing.T) {
-	if _, err := parseIntent([]string{"   ", "\n"}); err == nil {
+	if _, err := parseIntent([]string{"   ", "\n"}, intentOptions{}); err == nil {
 		t.Fatalf("parseIntent should error on empty args")
 	}
 }
@@ -341,11 +342,102 @@ func TestParseIntentRejectsEmptyStdin(t *testing.T) {
 	os.Stdin = r
 	defer func() { os.Stdin = origStdin }()
 
-	if _, err := parseIntent(nil); err == nil {
+	if _, err := parseIntent(nil, intentOptions{}); err == nil {
 		t.Fatalf("parseIntent should error on empty stdin")
 	}
 }
 
+func TestParseIntentCombinesSources(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	if _, err := w.WriteString("ticket body\n"); err != nil {
+		t.Fatalf("write stdin: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close pipe writer: %v", err)
+	}
+	origStdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = origStdin }()
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.md")
+	second := filepath.Join(dir, "second.md")
+	if err := os.WriteFile(first, []byte("from first\n"), 0o644); err != nil {
+		t.Fatalf("write first: %v", err)
+	}
+	if err := os.WriteFile(second, []byte("from second\n"), 0o644); err != nil {
+		t.Fatalf("write second: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var sources []intentSource
+	fs.Var(intentSourceFlag{sources: &sources}, "intent", "")
+	fs.Var(intentSourceFlag{sources: &sources, file: true}, "intent-file", "")
+	if err := fs.Parse([]string{"--intent-file", second, "--intent", "-", "--intent-file", first, "summarize", "it"}); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+
+	intent, err := parseIntent(fs.Args(), intentOptions{sources: sources})
+	if err != nil {
+		t.Fatalf("parseIntent: %v", err)
+	}
+	want := "from second\n\nticket body\n\nfrom first\n\nsummarize it"
+	if intent != want {
+		t.Fatalf("parseIntent = %q, want %q", intent, want)
+	}
+}
+
+func TestParseIntentMergesFrontMatter(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "spec.md")
+	if err := os.WriteFile(spec, []byte("---\nobjectives: [fast, small]\nrisks: none\n---\nSpec body\n"), 0o644); err != nil {
+		t.Fatalf("write spec: %v", err)
+	}
+	intent, err := parseIntent(nil, intentOptions{sources: []intentSource{
+		{value: "---\nrisks: outages\n---\nShip it"},
+		{file: true, value: spec},
+	}})
+	if err != nil {
+		t.Fatalf("parseIntent: %v", err)
+	}
+	doc, err := parseIntentDocument(intent)
+	if err != nil {
+		t.Fatalf("parseIntentDocument(%q): %v", intent, err)
+	}
+	if doc.body != "Ship it\n\nSpec body" || doc.fields["goals"] != "- fast\n- small" || doc.fields["risks"] != "none" {
+		t.Fatalf("doc = %+v", doc)
+	}
+}
+
+func TestParseIntentRejectsBadSources(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.md")
+	if err := os.WriteFile(empty, []byte("\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	cases := map[string][]intentSource{
+		"intent file " + empty + " is empty": {{file: true, value: empty}},
+		"read intent file":                   {{file: true, value: filepath.Join(t.TempDir(), "missing.md")}},
+		"stdin can only be read once":        {{value: "-"}, {value: "-"}},
+		"--intent is empty":                  {{value: "  "}},
+	}
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatalf("open devnull: %v", err)
+	}
+	defer func() { _ = devNull.Close() }()
+	origStdin := os.Stdin
+	os.Stdin = devNull
+	defer func() { os.Stdin = origStdin }()
+
+	for want, sources := range cases {
+		if _, err := parseIntent(nil, intentOptions{sources: sources}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q error, got %v", want, err)
+		}
+	}
+}
+
 func TestHandleGenerateDryRun(t *testing.T) {
 	configDir := filepath.Join(t.TempDir(), "cfg")
 	if err := ensureConfigStructure(configDir); err != nil {
@@ -564,3 +656,85 @@ func TestHandleGenerateHelpShowsUsage(t *testing.T) {
 		t.Fatalf("help output missing flags: %s", output)
 	}
 }
+
+func TestHandleGenerateFillsPlaceholdersFromSet(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	if err := bootstrapDefaults(configDir); err != nil {
+		t.Fatalf("bootstrapDefaults: %v", err)
+	}
+
+	tmpl := "Goals: {{goals}}\nRisks: {{risks}}\n"
+	if err := os.WriteFile(filepath.Join(configDir, templatesDirName, "custom.md"), []byte(tmpl), 0o644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	workdir := t.TempDir()
+	origWD, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	defer func() {
+		_ = os.Chdir(origWD)
+	}()
+	if err := os.Chdir(workdir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	if err := handleGenerate(configDir, []string{"-t", "custom", "--set", "goals=ship fast", "ship"}); err != nil {
+		t.Fatalf("handleGenerate returned error: %v", err)
+	}
+
+	content, err := os.ReadFile(workPromptFilename)
+	if err != nil {
+		t.Fatalf("read work prompt: %v", err)
+	}
+	if !strings.Contains(string(content), "Goals: ship fast\nRisks: \n") {
+		t.Fatalf("work prompt placeholders not resolved: %s", string(content))
+	}
+	if strings.Contains(string(content), "{{") {
+		t.Fatalf("work prompt contains literal braces: %s", string(content))
+	}
+}
+
+func TestHandleTemplateShowExpanded(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	if err := bootstrapDefaults(configDir); err != nil {
+		t.Fatalf("bootstrapDefaults: %v", err)
+	}
+
+	show := func(args ...string) string {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("pipe: %v", err)
+		}
+		origStdout := os.Stdout
+		os.Stdout = w
+		err = handleTemplateCommand(configDir, append([]string{"show"}, args...))
+		_ = w.Close()
+		os.Stdout = origStdout
+		if err != nil {
+			t.Fatalf("template show %v: %v", args, err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("read pipe: %v", err)
+		}
+		return string(data)
+	}
+
+	raw := show("prd")
+	if !strings.Contains(raw, "{{> guidelines}}") {
+		t.Fatalf("template show should print the include directive: %s", raw)
+	}
+
+	expanded := show("--expanded", "prd")
+	if strings.Contains(expanded, "{{> guidelines}}") || !strings.Contains(expanded, "## Guidelines\n{{guidelines}}") {
+		t.Fatalf("template show --expanded should inline partials: %s", expanded)
+	}
+}
@@ -6,18 +6,16 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 )
 
-import "gopkg.in/yaml.v3"
-
 const (
 	envConfigDir        = "BEET_CONFIG_DIR"
 	defaultConfigFolder = ".beet"
 	templatesDirName    = "templates"
 	guidelinesDirName   = "guidelines"
 	packsDirName        = "packs"
+	partialsDirName     = "partials"
 	defaultTemplateName = "default.md"
 	defaultPackName     = "default.yaml"
 )
@@ -55,22 +53,34 @@ func ensureConfigStructure(dir string) error {
 		return fmt.Errorf("create packs dir: %w", err)
 	}
 
+	if err := os.MkdirAll(filepath.Join(dir, partialsDirName), 0o755); err != nil {
+		return fmt.Errorf("create partials dir: %w", err)
+	}
+
 	return nil
 }
 
 func copyDefaults(dir string) error {
+	_, err := copyTree(embeddedDefaults, "defaults", dir)
+	return err
+}
+
+// copyTree copies root from fsys into dir without overwriting existing files
+// and reports how many files it created. A failed copy removes whatever it
+// created.
+func copyTree(fsys fs.FS, root, dir string) (int, error) {
 	var createdFiles []string
 	var createdDirs []string
 
-	err := fs.WalkDir(embeddedDefaults, "defaults", func(path string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if path == "defaults" {
+		if path == root {
 			return nil
 		}
 
-		rel, err := filepath.Rel("defaults", path)
+		rel, err := filepath.Rel(root, path)
 		if err != nil {
 			return fmt.Errorf("rel path for %s: %w", path, err)
 		}
@@ -103,7 +113,7 @@ func copyDefaults(dir string) error {
 			return fmt.Errorf("check file %s: %w", target, statErr)
 		}
 
-		data, readErr := embeddedDefaults.ReadFile(path)
+		data, readErr := fs.ReadFile(fsys, path)
 		if readErr != nil {
 			return fmt.Errorf("read default %s: %w", path, readErr)
 		}
@@ -117,9 +127,9 @@ func copyDefaults(dir string) error {
 
 	if err != nil {
 		cleanupDefaults(createdFiles, createdDirs)
-		return err
+		return 0, err
 	}
-	return nil
+	return len(createdFiles), nil
 }
 
 func bootstrapDefaults(dir string) error {
@@ -173,45 +183,28 @@ func cleanupDefaults(files, dirs []string) {
 }
 
 func listTemplates(configDir string) ([]string, error) {
-	entries, err := os.ReadDir(filepath.Join(configDir, templatesDirName))
+	entries, err := layeredEntries(configDir, templatesDirName)
 	if err != nil {
-		return nil, fmt.Errorf("read templates: %w", err)
+		return nil, err
 	}
-
-	var names []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		names = append(names, entry.Name())
-	}
-
-	sort.Strings(names)
-	return names, nil
+	return sortedEntryNames(entries), nil
 }
 
 func listPacks(configDir string) ([]string, error) {
-	entries, err := os.ReadDir(filepath.Join(configDir, packsDirName))
+	entries, err := layeredEntries(configDir, packsDirName)
 	if err != nil {
-		return nil, fmt.Errorf("read packs: %w", err)
-	}
-
-	var names []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		names = append(names, entry.Name())
+		return nil, err
 	}
-
-	sort.Strings(names)
-	return names, nil
+	return sortedEntryNames(entries), nil
 }
 
 func loadTemplate(configDir, name string) (string, error) {
 	name = normalizeTemplateName(name)
 
-	path := filepath.Join(configDir, templatesDirName, name)
+	path, ok := findLayeredFile(configDir, templatesDirName, name)
+	if !ok {
+		path = filepath.Join(configDir, templatesDirName, name)
+	}
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return "", fmt.Errorf("load template %s: %w", name, err)
@@ -219,31 +212,47 @@ func loadTemplate(configDir, name string) (string, error) {
 	return string(b), nil
 }
 
-func loadGuidelines(configDir string) ([]guideline, error) {
-	dir := filepath.Join(configDir, guidelinesDirName)
-	entries, err := os.ReadDir(dir)
+func loadPartial(configDir, name string) (string, error) {
+	name = normalizeTemplateName(name)
+
+	path, ok := findLayeredFile(configDir, partialsDirName, name)
+	if !ok {
+		path = filepath.Join(configDir, partialsDirName, filepath.FromSlash(name))
+	}
+	b, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("read guidelines: %w", err)
+		return "", fmt.Errorf("load partial %s: %w", name, err)
 	}
+	return string(b), nil
+}
 
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Name() < entries[j].Name()
-	})
+func partialsFrom(configDir string) partialLoader {
+	return func(name string) (string, error) {
+		return loadPartial(configDir, name)
+	}
+}
+
+func loadGuidelines(configDir string) ([]guideline, error) {
+	entries, err := layeredEntries(configDir, guidelinesDirName)
+	if err != nil {
+		return nil, err
+	}
 
 	var out []guideline
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	for _, file := range sortedEntryNames(entries) {
+		name := strings.TrimSuffix(file, filepath.Ext(file))
+		content, err := os.ReadFile(entries[file])
+		if err != nil {
+			return nil, fmt.Errorf("read guideline %s: %w", file, err)
 		}
-
-		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
-		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		g, err := parseGuideline(name, entries[file], string(content))
 		if err != nil {
-			return nil, fmt.Errorf("read guideline %s: %w", entry.Name(), err)
+			return nil, err
 		}
-		out = append(out, guideline{name: name, content: string(content)})
+		out = append(out, g)
 	}
 
+	sortGuidelines(out)
 	return out, nil
 }
 
@@ -257,55 +266,6 @@ func normalizeTemplateName(name string) string {
 	return name
 }
 
-type pack struct {
-	Outputs []packOutput `yaml:"outputs"`
-}
-
-type packOutput struct {
-	File     string `yaml:"file"`
-	Template string `yaml:"template"`
-}
-
-func normalizePackName(name string) string {
-	if name == "" {
-		name = defaultPackName
-	}
-	if !strings.HasSuffix(name, ".yaml") {
-		name += ".yaml"
-	}
-	return name
-}
-
-func loadPack(configDir, name string) (pack, error) {
-	name = normalizePackName(name)
-	path := filepath.Join(configDir, packsDirName, name)
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return pack{}, fmt.Errorf("load pack %s: %w", name, err)
-	}
-
-	var p pack
-	if err := yaml.Unmarshal(data, &p); err != nil {
-		return pack{}, fmt.Errorf("parse pack %s: %w", name, err)
-	}
-
-	if len(p.Outputs) == 0 {
-		return pack{}, fmt.Errorf("pack %s has no outputs", name)
-	}
-
-	for i, out := range p.Outputs {
-		if strings.TrimSpace(out.File) == "" {
-			return pack{}, fmt.Errorf("pack %s output %d missing file", name, i)
-		}
-		if strings.TrimSpace(out.Template) == "" {
-			return pack{}, fmt.Errorf("pack %s output %d missing template", name, i)
-		}
-	}
-
-	return p, nil
-}
-
 func requireConfigState(configDir string) error {
 	packs, err := listPacks(configDir)
 	if err != nil {
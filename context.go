@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultContextBudget = 6000
+	contextCommitCount   = 10
+	gitContextTimeout    = 5 * time.Second
+	truncatedMarker      = "… (truncated)"
+)
+
+var defaultContextIgnores = []string{".git", "node_modules", "vendor", ".beet", ".DS_Store", "dist", "build", "target"}
+
+var agentFileNames = []string{"AGENTS.md", "agents.md", "CLAUDE.md", "GEMINI.md", ".cursorrules", ".github/copilot-instructions.md"}
+
+var readmeNames = []string{"README.md", "README", "README.rst", "README.txt"}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+
+// collectRepoContext summarizes root for the {{background}} placeholder. The
+// output only depends on the files and git history, so repeated runs on the
+// same commit produce identical prompts (and cache hits); any new commit,
+// including one of the generated files, changes the summary. outputs are the
+// files beet is about to generate; they are left out so a run's own outputs
+// never feed back into it.
+func collectRepoContext(root string, budget int, outputs []string) (string, error) {
+	if budget <= 0 {
+		return "", fmt.Errorf("context budget must be positive, got %d", budget)
+	}
+	ignore, err := loadContextIgnores(root)
+	if err != nil {
+		return "", err
+	}
+	for _, out := range outputs {
+		ignore = append(ignore, globEscaper.Replace(filepath.ToSlash(filepath.Clean(out))))
+	}
+
+	sections := []struct {
+		title string
+		share int
+		body  string
+	}{
+		{title: "Repository layout", share: 35, body: repoTree(root, ignore)},
+		{title: "README excerpt", share: 35, body: readmeExcerpt(root, ignore)},
+		{title: "Recent commits", share: 20, body: recentCommits(root)},
+		{title: "Agent files", share: 10, body: agentFiles(root, ignore)},
+	}
+
+	var parts []string
+	for _, s := range sections {
+		if strings.TrimSpace(s.body) == "" {
+			continue
+		}
+		body := truncateToBudget(strings.TrimSpace(s.body), budget*s.share/100)
+		parts = append(parts, "### "+s.title+"\n"+body)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// loadContextIgnores returns the default ignores plus the simple patterns of
+// the root .gitignore. Negations are not supported and are skipped.
+func loadContextIgnores(root string) ([]string, error) {
+	patterns := append([]string(nil), defaultContextIgnores...)
+	data, err := os.ReadFile(filepath.Join(root, ".gitignore"))
+	if os.IsNotExist(err) {
+		return patterns, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read .gitignore: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		patterns = append(patterns, strings.Trim(line, "/"))
+	}
+	return patterns, nil
+}
+
+func contextIgnored(rel string, patterns []string) bool {
+	rel = filepath.ToSlash(rel)
+	base := path.Base(rel)
+	for _, pattern := range patterns {
+		target := base
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func repoTree(root string, ignore []string) string {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return ""
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var lines []string
+	for _, entry := range entries {
+		if contextIgnored(entry.Name(), ignore) {
+			continue
+		}
+		full := filepath.Join(root, entry.Name())
+		if entry.IsDir() {
+			files, size := dirStats(root, full, ignore)
+			if files == 0 {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("- %s/ (%d files, %s)", entry.Name(), files, formatSize(size)))
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s (%s)", entry.Name(), formatSize(info.Size())))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func dirStats(root, dir string, ignore []string) (int, int64) {
+	files := 0
+	var size int64
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		if p != dir && contextIgnored(rel, ignore) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				files++
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return files, size
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+func readmeExcerpt(root string, ignore []string) string {
+	for _, name := range readmeNames {
+		if contextIgnored(name, ignore) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err == nil {
+			return strings.ReplaceAll(string(data), "\r\n", "\n")
+		}
+	}
+	return ""
+}
+
+func recentCommits(root string) string {
+	out, err := runGit(root, "log", "-n", fmt.Sprint(contextCommitCount), "--format=%s")
+	if err != nil {
+		logVerbose("skipping git log for context: %v", err)
+		return ""
+	}
+	return bulletLines(out)
+}
+
+// runGit runs git in root and returns its stdout. Stderr is folded into the
+// error so callers can report why git refused.
+func runGit(root string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitContextTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", root}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+func bulletLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, "- "+line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func agentFiles(root string, ignore []string) string {
+	var lines []string
+	for _, name := range agentFileNames {
+		if contextIgnored(name, ignore) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s (%s)", name, formatSize(info.Size())))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateToBudget cuts text at the last line break that fits in budget
+// bytes, falling back to a rune boundary for a single long line.
+func truncateToBudget(text string, budget int) string {
+	if len(text) <= budget {
+		return text
+	}
+	limit := budget - len(truncatedMarker) - 1
+	if limit <= 0 {
+		return truncatedMarker
+	}
+	cut := strings.LastIndex(text[:limit], "\n")
+	if cut <= 0 {
+		cut = limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+	}
+	return strings.TrimRight(text[:cut], "\n ") + "\n" + truncatedMarker
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollectRepoContext(t *testing.T) {
+	root := t.TempDir()
+	writeConfigFile(t, root, "README.md", "# Demo\n\nA demo project.\n")
+	writeConfigFile(t, root, "cmd/app/main.go", "package main\n")
+	writeConfigFile(t, root, "cmd/app/main_test.go", "package main\n")
+	writeConfigFile(t, root, "cmd/app/secret.log", strings.Repeat("x", 5000))
+	writeConfigFile(t, root, "node_modules/dep/index.js", "x")
+	writeConfigFile(t, root, "CLAUDE.md", "be nice\n")
+	writeConfigFile(t, root, ".gitignore", "*.log\n/tmp/\n")
+	writeConfigFile(t, root, "tmp/cache.bin", "x")
+
+	got, err := collectRepoContext(root, defaultContextBudget, nil)
+	if err != nil {
+		t.Fatalf("collectRepoContext: %v", err)
+	}
+	for _, want := range []string{
+		"### Repository layout\n",
+		"- cmd/ (2 files, 26 B)",
+		"- README.md (24 B)",
+		"### README excerpt\n# Demo\n\nA demo project.",
+		"### Agent files\n- CLAUDE.md (8 B)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("context missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"node_modules", "tmp/", "secret.log"} {
+		if strings.Contains(got, unwanted) {
+			t.Fatalf("context should ignore %q:\n%s", unwanted, got)
+		}
+	}
+
+	again, err := collectRepoContext(root, defaultContextBudget, nil)
+	if err != nil || again != got {
+		t.Fatalf("context is not deterministic")
+	}
+}
+
+func TestCollectRepoContextExcludesOutputs(t *testing.T) {
+	root := t.TempDir()
+	writeConfigFile(t, root, "main.go", "package main\n")
+	outputs := []string{"PRD.md", "agents.md", "docs/PLAN.md"}
+
+	before, err := collectRepoContext(root, defaultContextBudget, outputs)
+	if err != nil {
+		t.Fatalf("collectRepoContext: %v", err)
+	}
+	writeConfigFile(t, root, "PRD.md", "generated\n")
+	writeConfigFile(t, root, "agents.md", "generated\n")
+	writeConfigFile(t, root, "docs/PLAN.md", "generated\n")
+	after, err := collectRepoContext(root, defaultContextBudget, outputs)
+	if err != nil {
+		t.Fatalf("collectRepoContext: %v", err)
+	}
+	if after != before {
+		t.Fatalf("outputs changed the context:\n%s\nvs\n%s", before, after)
+	}
+}
+
+func TestCollectRepoContextIncludesGitLog(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	writeConfigFile(t, root, "a.txt", "a")
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "Add a"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	got, err := collectRepoContext(root, defaultContextBudget, nil)
+	if err != nil {
+		t.Fatalf("collectRepoContext: %v", err)
+	}
+	if !strings.Contains(got, "### Recent commits\n- Add a") {
+		t.Fatalf("context missing commits:\n%s", got)
+	}
+}
+
+func TestCollectRepoContextHonorsBudget(t *testing.T) {
+	root := t.TempDir()
+	writeConfigFile(t, root, "README.md", strings.Repeat("line of readme text\n", 200))
+
+	got, err := collectRepoContext(root, 1000, nil)
+	if err != nil {
+		t.Fatalf("collectRepoContext: %v", err)
+	}
+	if len(got) > 1000 {
+		t.Fatalf("context is %d bytes, over budget", len(got))
+	}
+	if !strings.Contains(got, truncatedMarker) {
+		t.Fatalf("expected truncation marker:\n%s", got)
+	}
+
+	if _, err := collectRepoContext(root, 0, nil); err == nil {
+		t.Fatalf("expected error for zero budget")
+	}
+}
+
+func TestTruncateToBudgetKeepsRunes(t *testing.T) {
+	got := truncateToBudget(strings.Repeat("é", 50), 30)
+	if len(got) > 30 || !strings.HasSuffix(got, truncatedMarker) {
+		t.Fatalf("truncate = %q", got)
+	}
+	if !strings.HasPrefix(got, "é") || strings.ContainsRune(got, '\uFFFD') {
+		t.Fatalf("truncate split a rune: %q", got)
+	}
+}
+
+func TestHandleGenerateFillsBackgroundFromContext(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "{{background}}\n")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: OUT.md\n    template: t.md\n")
+
+	root := t.TempDir()
+	writeConfigFile(t, root, "README.md", "Project readme\n")
+	t.Chdir(root)
+
+	if err := handleGenerate(configDir, []string{"-p", "p", "--context", "ship"}); err != nil {
+		t.Fatalf("handleGenerate: %v", err)
+	}
+	out, err := os.ReadFile("OUT.md")
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !strings.Contains(string(out), "### README excerpt\nProject readme") {
+		t.Fatalf("background not filled:\n%s", out)
+	}
+}
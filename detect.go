@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const repoDetectDepth = 3
+
+// repoFacts are the facts beet infers from marker files in the working
+// directory and the few levels below it.
+type repoFacts struct {
+	languages []string
+	module    string
+	tools     []string
+}
+
+var (
+	goModulePattern    = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	tomlSectionPattern = regexp.MustCompile(`^\[([^\]]+)\]\s*$`)
+	tomlNamePattern    = regexp.MustCompile(`^name\s*=\s*["']([^"']+)["']`)
+)
+
+var languageMarkers = map[string]string{
+	"go.mod":           "go",
+	"package.json":     "javascript",
+	"tsconfig.json":    "typescript",
+	"pyproject.toml":   "python",
+	"requirements.txt": "python",
+	"setup.py":         "python",
+	"Cargo.toml":       "rust",
+}
+
+var toolMarkers = map[string]string{
+	"Dockerfile":          "docker",
+	"docker-compose.yml":  "docker",
+	"docker-compose.yaml": "docker",
+	".gitlab-ci.yml":      "gitlab-ci",
+	"Jenkinsfile":         "jenkins",
+	"Taskfile.yml":        "task",
+	"Makefile":            "make",
+}
+
+var toolDirMarkers = map[string]string{
+	".github/workflows": "github-actions",
+	".circleci":         "circleci",
+}
+
+var skippedDetectDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+}
+
+func detectRepo(root string) repoFacts {
+	languages := make(map[string]bool)
+	tools := make(map[string]bool)
+	packageDirs := make(map[string]bool)
+	tsconfigDirs := make(map[string]bool)
+
+	for rel, tool := range toolDirMarkers {
+		if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel))); err == nil && info.IsDir() {
+			tools[tool] = true
+		}
+	}
+
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			depth := strings.Count(filepath.ToSlash(rel), "/") + 1
+			if depth >= repoDetectDepth || strings.HasPrefix(d.Name(), ".") || skippedDetectDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch d.Name() {
+		case "package.json":
+			packageDirs[filepath.Dir(path)] = true
+		case "tsconfig.json":
+			tsconfigDirs[filepath.Dir(path)] = true
+		}
+		if lang, ok := languageMarkers[d.Name()]; ok {
+			languages[lang] = true
+		}
+		if tool, ok := toolMarkers[d.Name()]; ok {
+			tools[tool] = true
+		}
+		return nil
+	})
+
+	// A package with a tsconfig.json next to its package.json is TypeScript;
+	// JavaScript is only reported for packages without one.
+	delete(languages, "javascript")
+	for dir := range packageDirs {
+		if !tsconfigDirs[dir] {
+			languages["javascript"] = true
+		}
+	}
+
+	facts := repoFacts{
+		languages: sortedKeys(languages),
+		module:    detectModule(root),
+		tools:     sortedKeys(tools),
+	}
+	logVerbose("detected repo languages=%v module=%q tools=%v", facts.languages, facts.module, facts.tools)
+	return facts
+}
+
+// detectModule names the project from the first root-level manifest found.
+func detectModule(root string) string {
+	if data, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+		if m := goModulePattern.FindSubmatch(data); m != nil {
+			return string(m[1])
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "package.json")); err == nil {
+		var manifest struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(data, &manifest) == nil && manifest.Name != "" {
+			return manifest.Name
+		}
+	}
+	if name := tomlName(filepath.Join(root, "Cargo.toml"), "package"); name != "" {
+		return name
+	}
+	if name := tomlName(filepath.Join(root, "pyproject.toml"), "project", "tool.poetry"); name != "" {
+		return name
+	}
+	return ""
+}
+
+// tomlName reads `name = "..."` from one of the given sections without a
+// full TOML parser; manifests keep it on a single line in practice.
+func tomlName(path string, sections ...string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	current := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if m := tomlSectionPattern.FindStringSubmatch(line); m != nil {
+			current = strings.TrimSpace(m[1])
+			continue
+		}
+		for _, section := range sections {
+			if current != section {
+				continue
+			}
+			if m := tomlNamePattern.FindStringSubmatch(line); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+func (f repoFacts) placeholders() placeholderValues {
+	return placeholderValues{
+		"repo.languages": strings.Join(f.languages, ", "),
+		"repo.module":    f.module,
+		"repo.tools":     strings.Join(f.tools, ", "),
+	}
+}
+
+// sortedKeys returns nil for an empty set, so languages nobody detected read
+// as unknown rather than as none.
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
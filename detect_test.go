@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectRepoMonorepo(t *testing.T) {
+	root := t.TempDir()
+	writeConfigFile(t, root, "go.mod", "// comment\nmodule example.com/mono\n\ngo 1.22\n")
+	writeConfigFile(t, root, "web/package.json", `{"name": "web"}`)
+	writeConfigFile(t, root, "web/tsconfig.json", "{}")
+	writeConfigFile(t, root, "web/node_modules/dep/setup.py", "")
+	writeConfigFile(t, root, "Dockerfile", "FROM scratch\n")
+	writeConfigFile(t, root, ".github/workflows/ci.yml", "on: push\n")
+
+	facts := detectRepo(root)
+	if !reflect.DeepEqual(facts.languages, []string{"go", "typescript"}) {
+		t.Fatalf("languages = %v", facts.languages)
+	}
+	if facts.module != "example.com/mono" {
+		t.Fatalf("module = %q", facts.module)
+	}
+	if !reflect.DeepEqual(facts.tools, []string{"docker", "github-actions"}) {
+		t.Fatalf("tools = %v", facts.tools)
+	}
+
+	web := detectRepo(filepath.Join(root, "web"))
+	if !reflect.DeepEqual(web.languages, []string{"typescript"}) || web.module != "web" {
+		t.Fatalf("web facts = %+v", web)
+	}
+}
+
+func TestDetectModuleFromTomlManifests(t *testing.T) {
+	rust := t.TempDir()
+	writeConfigFile(t, rust, "Cargo.toml", "[workspace]\nname = \"nope\"\n\n[package]\nname = \"crate-x\"\nversion = \"0.1.0\"\n")
+	if got := detectRepo(rust); got.module != "crate-x" || !reflect.DeepEqual(got.languages, []string{"rust"}) {
+		t.Fatalf("rust facts = %+v", got)
+	}
+
+	py := t.TempDir()
+	writeConfigFile(t, py, "pyproject.toml", "[tool.poetry]\nname = 'pkg-y'\n")
+	writeConfigFile(t, py, "app.js/package.json", "{}")
+	if got := detectRepo(py); got.module != "pkg-y" || !reflect.DeepEqual(got.languages, []string{"javascript", "python"}) {
+		t.Fatalf("python facts = %+v", got)
+	}
+}
+
+func TestRepoPlaceholdersRender(t *testing.T) {
+	facts := repoFacts{languages: []string{"go", "typescript"}, module: "example.com/x"}
+	got, _, err := renderTemplateText("t.md", "{{repo.module}}\n{{#each repo.languages}}\n- {{.}}\n{{/each}}\nsha: ${{github.sha}}\n", facts.placeholders(), nil)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	want := "example.com/x\n- go\n- typescript\nsha: ${{github.sha}}\n"
+	if got != want {
+		t.Fatalf("render = %q, want %q", got, want)
+	}
+}
+
+func TestHandleGenerateSkipsGuidelinesForOtherLanguages(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "{{repo.languages}}\n{{guidelines}}\n")
+	writeConfigFile(t, configDir, "guidelines/go-style.md", "---\napplies_to:\n  languages: [go]\n---\nGO STYLE")
+	writeConfigFile(t, configDir, "guidelines/principles.md", "PRINCIPLES")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: OUT.md\n    template: t.md\n")
+
+	root := t.TempDir()
+	writeConfigFile(t, root, "go.mod", "module x\n")
+	writeConfigFile(t, root, "web/package.json", "{}")
+	writeConfigFile(t, root, "web/tsconfig.json", "{}")
+	t.Chdir(filepath.Join(root, "web"))
+
+	if err := handleGenerate(configDir, []string{"-p", "p", "ship"}); err != nil {
+		t.Fatalf("handleGenerate: %v", err)
+	}
+	out, err := os.ReadFile("OUT.md")
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if strings.Contains(string(out), "GO STYLE") || !strings.Contains(string(out), "PRINCIPLES") || !strings.Contains(string(out), "typescript") {
+		t.Fatalf("frontend output:\n%s", out)
+	}
+}
+
+func TestUndetectedLanguagesKeepScopedGuidelines(t *testing.T) {
+	root := t.TempDir()
+	facts := detectRepo(root)
+	if facts.languages != nil {
+		t.Fatalf("languages = %#v, want nil", facts.languages)
+	}
+
+	gs := []guideline{{name: "go-style", meta: guidelineMeta{AppliesTo: guidelineScope{Languages: []string{"go"}}}}}
+	if got := applicableGuidelines(gs, root, facts.languages); len(got) != 1 {
+		t.Fatalf("applicableGuidelines = %v, want go-style kept", got)
+	}
+}
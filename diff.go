@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// handleDiff renders the pack like generation would and shows what writing
+// it would change, without touching any file.
+func handleDiff(configDir string, args []string) error {
+	fs, opts := newGenerateFlags("diff")
+	stat := fs.Bool("stat", false, "show a per-file summary instead of the diff")
+	colorMode := fs.String("color", "auto", "colorize output: auto, always or never")
+	fs.Usage = func() {
+		usagePrintln(fs.Output(), "Usage: beet diff [--stat] [--color auto|always|never] [generate flags] [intent|file]")
+		usagePrintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	opts.args = fs.Args()
+
+	color, err := useColor(*colorMode, os.Stdout)
+	if err != nil {
+		return err
+	}
+	writeOpts, err := opts.writeOptions()
+	if err != nil {
+		return err
+	}
+
+	rendered, err := renderOutputs(configDir, opts)
+	if err != nil {
+		return err
+	}
+
+	var plans []outputPlan
+	for _, out := range rendered.outputs {
+		plan, err := planOutput(out.File, out.content, writeOpts.policyFor(out.packOutput))
+		if err != nil {
+			return err
+		}
+		if plan.conflict {
+			logWarning("%s already exists (on_exists: fail); generating stops there unless you pass --force", out.File)
+		}
+		plans = append(plans, plan)
+	}
+	return printPlanDiff(os.Stdout, plans, *stat, color)
+}
+
+func printPlanDiff(w io.Writer, plans []outputPlan, stat, color bool) error {
+	var stats []diffStat
+	var diffs []outputPlan
+	for _, plan := range plans {
+		if !plan.changed() {
+			logVerbose("%s: no changes", plan.path)
+			continue
+		}
+		diffs = append(diffs, plan)
+	}
+	if len(diffs) == 0 {
+		logVerbose("no outputs would change")
+		return nil
+	}
+
+	out := diffPrinter{w: w, color: color}
+	if stat {
+		out.w = io.Discard
+	}
+	for _, plan := range diffs {
+		oldName := plan.path
+		if !plan.exists {
+			oldName = ""
+		}
+		added, removed, err := out.unifiedDiff(oldName, plan.path, plan.old, plan.content)
+		if err != nil {
+			return err
+		}
+		stats = append(stats, diffStat{name: plan.path, added: added, removed: removed})
+	}
+	if stat {
+		return diffPrinter{w: w, color: color}.stat(stats)
+	}
+	return nil
+}
+
+// useColor resolves --color; auto colors only a terminal and honors NO_COLOR.
+func useColor(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("--color must be auto, always or never, got %q", mode)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrintPlanDiffSkipsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	same := filepath.Join(dir, "SAME.md")
+	changed := filepath.Join(dir, "CHANGED.md")
+	if err := os.WriteFile(same, []byte("same\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(changed, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var plans []outputPlan
+	for path, content := range map[string]string{same: "same\n", changed: "new\n"} {
+		plan, err := planOutput(path, content, onExistsDefault)
+		if err != nil {
+			t.Fatalf("plan %s: %v", path, err)
+		}
+		plans = append(plans, plan)
+	}
+
+	var buf bytes.Buffer
+	if err := printPlanDiff(&buf, plans, false, false); err != nil {
+		t.Fatalf("printPlanDiff: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "SAME.md") || !strings.Contains(out, "-old\n+new\n") {
+		t.Fatalf("unexpected diff:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := printPlanDiff(&buf, plans, true, false); err != nil {
+		t.Fatalf("printPlanDiff --stat: %v", err)
+	}
+	if !strings.Contains(buf.String(), "CHANGED.md | 2 +-") || !strings.Contains(buf.String(), " 1 file changed") {
+		t.Fatalf("unexpected stat:\n%s", buf.String())
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if on, err := useColor("always", f); err != nil || !on {
+		t.Fatalf("always: %v, %v", on, err)
+	}
+	if on, err := useColor("auto", f); err != nil || on {
+		t.Fatalf("auto on a regular file: %v, %v", on, err)
+	}
+	if _, err := useColor("sometimes", f); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
+
+func TestHandleDiffWritesNothing(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "{{intent}}\n")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: OUT.md\n    template: t.md\n")
+	t.Chdir(t.TempDir())
+
+	if err := handleGenerate(configDir, []string{"-p", "p", "first intent"}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	before, err := os.ReadFile("OUT.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handleDiff(configDir, []string{"-p", "p", "--color", "never", "second intent"}); err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	after, err := os.ReadFile("OUT.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Fatalf("diff modified OUT.md: %q", after)
+	}
+}
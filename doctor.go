@@ -18,37 +18,34 @@ const envCLIBinary = "BEET_CLI_PATH"
 
 var cliPriority = []string{"codex", "copilot", "claude"}
 
-func detectPreferredCLI() (detectedCLI, bool) {
-	for _, name := range cliPriority {
-		path, err := exec.LookPath(name)
-		if err == nil {
-			logVerbose("preferred CLI %s found at %s", name, path)
-			return detectedCLI{name: name, path: path}, true
-		}
-		logVerbose("preferred CLI %s not found: %v", name, err)
+func lookupCLI(name, binary string) (detectedCLI, bool) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		logVerbose("CLI %s unavailable: %v", name, err)
+		return detectedCLI{}, false
 	}
-	return detectedCLI{}, false
+	logVerbose("detected CLI %s at %s", name, path)
+	return detectedCLI{name: name, path: path}, true
 }
 
-func detectAllCLIs() []detectedCLI {
-	var out []detectedCLI
-	for _, name := range cliPriority {
-		path, err := exec.LookPath(name)
-		if err == nil {
-			logVerbose("detected CLI %s at %s", name, path)
-			out = append(out, detectedCLI{name: name, path: path})
-			continue
-		}
-		logVerbose("CLI %s unavailable: %v", name, err)
+func detectPreferredCLI() (detectedCLI, bool) {
+	found := newAdapterRegistry().detectAll()
+	if len(found) == 0 {
+		return detectedCLI{}, false
 	}
-	return out
+	logVerbose("preferred CLI %s found at %s", found[0].name, found[0].path)
+	return found[0], true
 }
 
-func runDoctor(w io.Writer) error {
+func runDoctor(w io.Writer, configDir string) error {
 	logVerbose("running doctor diagnostics")
-	found := detectAllCLIs()
+	reg, err := loadAdapters(configDir)
+	if err != nil {
+		return err
+	}
+	found := reg.detectAll()
 	logVerbose("detected %d CLI candidates", len(found))
-	for _, name := range cliPriority {
+	for _, name := range reg.priority {
 		if _, err := fmt.Fprintf(w, "%s: ", name); err != nil {
 			return err
 		}
@@ -64,13 +61,24 @@ func runDoctor(w io.Writer) error {
 				return err
 			}
 		} else {
-			if _, err := fmt.Fprintf(w, "found at %s\n", path); err != nil {
+			a := reg.lookup(name)
+			if _, err := fmt.Fprintf(w, "found at %s (adapter v%s, prompt via %s)\n", path, a.Version, a.Prompt); err != nil {
 				return err
 			}
 		}
 	}
 
-	if override, ok, err := detectCLIOverride(); err != nil {
+	if project, ok := findProjectConfigDir(configDir); ok {
+		if _, err := fmt.Fprintf(w, "project config: %s (layered over %s)\n", project, configDir); err != nil {
+			return err
+		}
+	}
+
+	if raw := strings.TrimSpace(os.Getenv(envCLIBinary)); isBuiltinBackend(raw) {
+		if _, err := fmt.Fprintf(w, "%s override: %s (built-in backend)\n", envCLIBinary, raw); err != nil {
+			return err
+		}
+	} else if override, ok, err := detectCLIOverride(); err != nil {
 		if _, writeErr := fmt.Fprintf(w, "%s: %v\n", envCLIBinary, err); writeErr != nil {
 			return writeErr
 		}
@@ -90,18 +98,8 @@ func runDoctor(w io.Writer) error {
 }
 
 func requireCLI() (detectedCLI, error) {
-	if override, ok, err := detectCLIOverride(); err != nil {
-		return detectedCLI{}, err
-	} else if ok {
-		logVerbose("using CLI override %s (%s)", override.name, override.path)
-		return override, nil
-	}
-	cli, ok := detectPreferredCLI()
-	if !ok {
-		return detectedCLI{}, fmt.Errorf("no supported CLI found; install Codex CLI, Copilot CLI, or Claude Code CLI")
-	}
-	logVerbose("selected CLI %s (%s)", cli.name, cli.path)
-	return cli, nil
+	cli, _, err := newAdapterRegistry().require()
+	return cli, err
 }
 
 func detectCLIOverride() (detectedCLI, bool, error) {
@@ -110,6 +108,10 @@ func detectCLIOverride() (detectedCLI, bool, error) {
 		return detectedCLI{}, false, nil
 	}
 	logVerbose("%s override requested: %s", envCLIBinary, raw)
+	if isBuiltinBackend(raw) {
+		logVerbose("%s selects built-in backend %s", envCLIBinary, raw)
+		return detectedCLI{}, false, nil
+	}
 	path, err := exec.LookPath(raw)
 	if err != nil {
 		logVerbose("%s lookup failed: %v", envCLIBinary, err)
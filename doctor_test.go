@@ -38,7 +38,7 @@ func TestRunDoctorReportsMissing(t *testing.T) {
 	t.Setenv("PATH", "")
 
 	var b strings.Builder
-	if err := runDoctor(&b); err != nil {
+	if err := runDoctor(&b, t.TempDir()); err != nil {
 		t.Fatalf("runDoctor error: %v", err)
 	}
 
@@ -211,3 +211,105 @@ func TestE2EGenerateComprehensivePack(t *testing.T) {
 		}
 	}
 }
+
+func TestE2EGenerateRefinesThroughCLI(t *testing.T) {
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+
+	workdir := t.TempDir()
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	bin := filepath.Join(t.TempDir(), "beet-e2e")
+	cliBinDir := t.TempDir()
+
+	build := exec.Command("go", "build", "-o", bin, ".")
+	build.Dir = root
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, string(out))
+	}
+
+	cliScript := filepath.Join(cliBinDir, "codex")
+	if err := os.WriteFile(cliScript, []byte("#!/bin/sh\necho refined:\n/bin/cat\n"), 0o755); err != nil {
+		t.Fatalf("write fake codex: %v", err)
+	}
+
+	cmd := exec.Command(bin, "--refine", "ship", "it")
+	cmd.Dir = workdir
+	cmd.Env = append(os.Environ(),
+		"BEET_CONFIG_DIR="+configDir,
+		"HOME="+workdir,
+		"PATH="+cliBinDir+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"BEET_CLI_TIMEOUT=30s",
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("beet execution failed: %v\n%s", err, string(out))
+	}
+
+	content, err := os.ReadFile(filepath.Join(workdir, workPromptFilename))
+	if err != nil {
+		t.Fatalf("read WORK_PROMPT.md: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "refined:\n") || !strings.Contains(string(content), "ship it") {
+		t.Fatalf("work prompt not refined through CLI: %s", string(content))
+	}
+}
+
+func TestE2ERefineRecordAndReplayFixtures(t *testing.T) {
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	fixtures := filepath.Join(t.TempDir(), "fixtures")
+	bin := filepath.Join(t.TempDir(), "beet-e2e")
+	cliBinDir := t.TempDir()
+
+	build := exec.Command("go", "build", "-o", bin, ".")
+	build.Dir = root
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, string(out))
+	}
+
+	cliScript := filepath.Join(cliBinDir, "codex")
+	if err := os.WriteFile(cliScript, []byte("#!/bin/sh\necho recorded answer\n"), 0o755); err != nil {
+		t.Fatalf("write fake codex: %v", err)
+	}
+
+	run := func(workdir, path string, args ...string) {
+		cmd := exec.Command(bin, args...)
+		cmd.Dir = workdir
+		cmd.Env = append(os.Environ(),
+			"BEET_CONFIG_DIR="+configDir,
+			"BEET_FIXTURES_DIR="+fixtures,
+			"BEET_CLI_PATH=",
+			"HOME="+workdir,
+			"PATH="+path,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("beet %v failed: %v\n%s", args, err, string(out))
+		}
+	}
+
+	recordDir := t.TempDir()
+	run(recordDir, cliBinDir+string(os.PathListSeparator)+os.Getenv("PATH"), "--refine", "--record-fixtures", "ship", "it")
+
+	replayDir := t.TempDir()
+	run(replayDir, os.Getenv("PATH"), "--cli", "fixture", "ship", "it")
+
+	for _, name := range []string{workPromptFilename, agentsFilename} {
+		recorded, err := os.ReadFile(filepath.Join(recordDir, name))
+		if err != nil {
+			t.Fatalf("read recorded %s: %v", name, err)
+		}
+		replayed, err := os.ReadFile(filepath.Join(replayDir, name))
+		if err != nil {
+			t.Fatalf("read replayed %s: %v", name, err)
+		}
+		if string(recorded) != "recorded answer\n" || string(replayed) != string(recorded) {
+			t.Fatalf("%s replay mismatch: recorded %q, replayed %q", name, string(recorded), string(replayed))
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const defaultDiffBudget = 12000
+
+var defaultDiffBases = []string{"main", "master"}
+
+// diffBaseFlag backs --from-diff. It acts as a boolean so `--from-diff` alone
+// picks the base automatically, while `--from-diff=<ref>` or --diff-base
+// names it.
+type diffBaseFlag struct {
+	enabled bool
+	base    string
+}
+
+func (f *diffBaseFlag) String() string {
+	if f == nil || !f.enabled {
+		return ""
+	}
+	return f.base
+}
+
+func (f *diffBaseFlag) Set(value string) error {
+	switch value {
+	case "true":
+		f.enabled = true
+	case "false":
+		f.enabled, f.base = false, ""
+	default:
+		f.enabled, f.base = true, strings.TrimSpace(value)
+	}
+	return nil
+}
+
+func (f *diffBaseFlag) IsBoolFlag() bool { return true }
+
+// diffBaseRefFlag backs --diff-base, which takes the ref as a separate
+// argument and implies --from-diff.
+type diffBaseRefFlag struct {
+	flag *diffBaseFlag
+}
+
+func (f diffBaseRefFlag) String() string {
+	if f.flag == nil {
+		return ""
+	}
+	return f.flag.base
+}
+
+func (f diffBaseRefFlag) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("--diff-base needs a ref")
+	}
+	f.flag.enabled, f.flag.base = true, value
+	return nil
+}
+
+// diffIntent describes the work in progress in root relative to base: the
+// commits since the merge base, the changed and untracked files, and the diff
+// of the working tree, truncated to budget bytes.
+func diffIntent(root, base string, budget int) (string, error) {
+	if budget <= 0 {
+		return "", fmt.Errorf("diff budget must be positive, got %d", budget)
+	}
+	if base == "" {
+		detected, err := defaultDiffBase(root)
+		if err != nil {
+			return "", err
+		}
+		base = detected
+	}
+	mergeBase, err := runGit(root, "merge-base", base, "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("find merge base with %s: %w", base, err)
+	}
+	mergeBase = strings.TrimSpace(mergeBase)
+
+	commits, err := runGit(root, "log", "--reverse", "--format=%s", mergeBase+"..HEAD")
+	if err != nil {
+		return "", fmt.Errorf("list commits: %w", err)
+	}
+	numstat, err := runGit(root, "diff", "--numstat", mergeBase)
+	if err != nil {
+		return "", fmt.Errorf("list changed files: %w", err)
+	}
+	untracked, err := runGit(root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return "", fmt.Errorf("list untracked files: %w", err)
+	}
+	patch, err := runGit(root, "diff", mergeBase)
+	if err != nil {
+		return "", fmt.Errorf("diff against %s: %w", base, err)
+	}
+
+	files := changedFiles(numstat, untracked)
+	if len(files) == 0 && strings.TrimSpace(commits) == "" {
+		return "", fmt.Errorf("no changes against %s", base)
+	}
+
+	branch, err := runGit(root, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("read current branch: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Work in progress\n\nBranch `%s` against `%s` (merge base %s).\n", strings.TrimSpace(branch), base, shortHash(mergeBase))
+	if commits := bulletLines(commits); commits != "" {
+		b.WriteString("\n### Commits\n" + commits + "\n")
+	}
+	if len(files) > 0 {
+		b.WriteString("\n### Changed files\n" + strings.Join(files, "\n") + "\n")
+	}
+	if patch = strings.TrimSpace(patch); patch != "" {
+		// Keep the fence and headings intact; only the hunks are cut. The
+		// fence outgrows any backtick run in the patch so fenced Markdown in
+		// it can't close it early.
+		fence := strings.Repeat("`", max(3, longestRun(patch, '`')+1))
+		patch = truncateToBudget(patch, budget-b.Len()-len("\n### Diff\n"+fence+"diff\n\n"+fence+"\n"))
+		b.WriteString("\n### Diff\n" + fence + "diff\n" + patch + "\n" + fence + "\n")
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// longestRun returns the length of the longest run of c in text.
+func longestRun(text string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(text); i++ {
+		if text[i] != c {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// isGitRef reports whether name resolves to a commit in the repository at
+// root.
+func isGitRef(root, name string) bool {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return false
+	}
+	_, err := runGit(root, "rev-parse", "--verify", "--quiet", name+"^{commit}")
+	return err == nil
+}
+
+// defaultDiffBase prefers the remote's default branch, then a local main or
+// master.
+func defaultDiffBase(root string) (string, error) {
+	if ref, err := runGit(root, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil && strings.TrimSpace(ref) != "" {
+		return strings.TrimSpace(ref), nil
+	}
+	for _, name := range defaultDiffBases {
+		if _, err := runGit(root, "rev-parse", "--verify", "--quiet", name); err == nil {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("cannot determine a diff base; pass --from-diff=<ref>")
+}
+
+func changedFiles(numstat, untracked string) []string {
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(numstat), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "-" {
+			files = append(files, fmt.Sprintf("- %s (binary)", fields[2]))
+			continue
+		}
+		files = append(files, fmt.Sprintf("- %s (+%s -%s)", fields[2], fields[0], fields[1]))
+	}
+	for _, name := range strings.Split(strings.TrimSpace(untracked), "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			files = append(files, fmt.Sprintf("- %s (untracked)", name))
+		}
+	}
+	return files
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git(t, root, "init", "-q", "-b", "main")
+	writeConfigFile(t, root, "app.go", "package app\n")
+	git(t, root, "add", ".")
+	git(t, root, "commit", "-q", "-m", "Initial commit")
+	return root
+}
+
+func git(t *testing.T, root string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+	cmd.Dir = root
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestDiffIntentDescribesWorkInProgress(t *testing.T) {
+	root := gitRepo(t)
+	git(t, root, "checkout", "-q", "-b", "feature")
+	writeConfigFile(t, root, "app.go", "package app\n\nfunc Half() {}\n")
+	git(t, root, "commit", "-q", "-am", "Start Half")
+	writeConfigFile(t, root, "app.go", "package app\n\nfunc Half() { todo() }\n")
+	writeConfigFile(t, root, "notes.txt", "new\n")
+
+	got, err := diffIntent(root, "", defaultDiffBudget)
+	if err != nil {
+		t.Fatalf("diffIntent: %v", err)
+	}
+	for _, want := range []string{
+		"Branch `feature` against `main`",
+		"### Commits\n- Start Half",
+		"- app.go (+2 -0)",
+		"- notes.txt (untracked)",
+		"```diff\ndiff --git a/app.go b/app.go",
+		"+func Half() { todo() }",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("diff intent missing %q:\n%s", want, got)
+		}
+	}
+
+	small, err := diffIntent(root, "main", 300)
+	if err != nil {
+		t.Fatalf("diffIntent: %v", err)
+	}
+	if len(small) > 300 || !strings.Contains(small, truncatedMarker) || !strings.HasSuffix(small, "```") {
+		t.Fatalf("diff intent not truncated to budget (%d bytes):\n%s", len(small), small)
+	}
+}
+
+func TestDiffIntentFenceOutgrowsPatch(t *testing.T) {
+	root := gitRepo(t)
+	writeConfigFile(t, root, "README.md", "# App\n")
+	git(t, root, "add", "README.md")
+	git(t, root, "commit", "-q", "-m", "Add README")
+	writeConfigFile(t, root, "README.md", "# App\n\n```sh\ngo run .\n```\n")
+
+	got, err := diffIntent(root, "main", defaultDiffBudget)
+	if err != nil {
+		t.Fatalf("diffIntent: %v", err)
+	}
+	if !strings.Contains(got, "\n````diff\n") || !strings.HasSuffix(got, "\n````") {
+		t.Fatalf("diff fence not longer than the patch's:\n%s", got)
+	}
+}
+
+func TestDiffIntentErrors(t *testing.T) {
+	root := gitRepo(t)
+	if _, err := diffIntent(root, "main", defaultDiffBudget); err == nil || !strings.Contains(err.Error(), "no changes against main") {
+		t.Fatalf("expected no changes error, got %v", err)
+	}
+	if _, err := diffIntent(root, "nope", defaultDiffBudget); err == nil || !strings.Contains(err.Error(), "find merge base with nope") {
+		t.Fatalf("expected merge base error, got %v", err)
+	}
+}
+
+func TestParseIntentFromDiff(t *testing.T) {
+	root := gitRepo(t)
+	writeConfigFile(t, root, "app.go", "package app // wip\n")
+	t.Chdir(root)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var fromDiff diffBaseFlag
+	fs.Var(&fromDiff, "from-diff", "")
+	if err := fs.Parse([]string{"--from-diff=main", "finish", "this"}); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+
+	intent, err := parseIntent(fs.Args(), intentOptions{fromDiff: fromDiff})
+	if err != nil {
+		t.Fatalf("parseIntent: %v", err)
+	}
+	if !strings.HasPrefix(intent, "finish this\n\n## Work in progress") || !strings.Contains(intent, "- app.go (+1 -1)") {
+		t.Fatalf("intent = %q", intent)
+	}
+}
+
+func TestParseIntentFromDiffWarnsAboutRefArg(t *testing.T) {
+	root := gitRepo(t)
+	writeConfigFile(t, root, "app.go", "package app // wip\n")
+	t.Chdir(root)
+	warnings := captureWarnings(t)
+
+	intent, err := parseIntent([]string{"main"}, intentOptions{fromDiff: diffBaseFlag{enabled: true}})
+	if err != nil {
+		t.Fatalf("parseIntent: %v", err)
+	}
+	if !strings.HasPrefix(intent, "main\n\n") || !strings.Contains(warnings.String(), "use --diff-base main") {
+		t.Fatalf("intent = %q, warnings = %q", intent, warnings.String())
+	}
+
+	warnings.Reset()
+	if _, err := parseIntent([]string{"finish", "this"}, intentOptions{fromDiff: diffBaseFlag{enabled: true}}); err != nil {
+		t.Fatalf("parseIntent: %v", err)
+	}
+	if warnings.Len() != 0 {
+		t.Fatalf("unexpected warnings: %q", warnings.String())
+	}
+}
+
+func TestDiffBaseFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"--diff-base", "develop", "finish"},
+		{"--from-diff", "--diff-base", "develop", "finish"},
+		{"--diff-base", "develop", "--from-diff", "finish"},
+		{"--from-diff=develop", "finish"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		var fromDiff diffBaseFlag
+		fs.Var(&fromDiff, "from-diff", "")
+		fs.Var(diffBaseRefFlag{flag: &fromDiff}, "diff-base", "")
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("parse %v: %v", args, err)
+		}
+		if !fromDiff.enabled || fromDiff.base != "develop" || !reflect.DeepEqual(fs.Args(), []string{"finish"}) {
+			t.Fatalf("%v: got %+v, args %v", args, fromDiff, fs.Args())
+		}
+	}
+}
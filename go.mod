@@ -4,4 +4,4 @@ go 1.25
 
 require github.com/pkg/browser v0.0.0-20180916011732-0a3d74bf9ce4
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+import "gopkg.in/yaml.v3"
+
+// guidelineMeta is the optional YAML front matter of a guideline file.
+type guidelineMeta struct {
+	Title     string         `yaml:"title"`
+	Tags      []string       `yaml:"tags"`
+	Priority  int            `yaml:"priority"`
+	AppliesTo guidelineScope `yaml:"applies_to"`
+}
+
+// guidelineScope limits a guideline to repositories using one of Languages
+// and containing a file matching one of Paths. Empty lists match anything.
+type guidelineScope struct {
+	Languages []string `yaml:"languages"`
+	Paths     []string `yaml:"paths"`
+}
+
+func parseGuideline(name, source, text string) (guideline, error) {
+	front, body, ok := cutFrontMatter(text)
+	if !ok {
+		return guideline{name: name, content: text, source: source}, nil
+	}
+
+	var meta guidelineMeta
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(front)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&meta); err != nil && !errors.Is(err, io.EOF) {
+		return guideline{}, fmt.Errorf("parse guideline %s front matter: %w", name, err)
+	}
+	for i, tag := range meta.Tags {
+		meta.Tags[i] = strings.ToLower(strings.TrimSpace(tag))
+	}
+	return guideline{name: name, content: strings.TrimLeft(body, "\n"), source: source, meta: meta}, nil
+}
+
+// sortGuidelines orders guidelines by descending priority, then by name.
+func sortGuidelines(gs []guideline) {
+	sort.SliceStable(gs, func(i, j int) bool {
+		if gs[i].meta.Priority != gs[j].meta.Priority {
+			return gs[i].meta.Priority > gs[j].meta.Priority
+		}
+		return gs[i].name < gs[j].name
+	})
+}
+
+func (m guidelineMeta) hasAnyTag(tags []string) bool {
+	for _, want := range tags {
+		for _, tag := range m.Tags {
+			if strings.EqualFold(tag, strings.TrimSpace(want)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applies reports whether the scope matches the repository at root.
+// languages is nil when they are unknown, in which case they don't filter.
+func (s guidelineScope) applies(root string, languages []string) bool {
+	if len(s.Languages) > 0 && languages != nil && !overlapsFold(s.Languages, languages) {
+		return false
+	}
+	if len(s.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range s.Paths {
+		if matches, _ := filepath.Glob(filepath.Join(root, filepath.FromSlash(pattern))); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s guidelineScope) String() string {
+	var parts []string
+	if len(s.Languages) > 0 {
+		parts = append(parts, "languages "+strings.Join(s.Languages, ","))
+	}
+	if len(s.Paths) > 0 {
+		parts = append(parts, "paths "+strings.Join(s.Paths, ","))
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, "; ")
+}
+
+func overlapsFold(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(strings.TrimSpace(x), strings.TrimSpace(y)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func applicableGuidelines(gs []guideline, root string, languages []string) []guideline {
+	var out []guideline
+	for _, g := range gs {
+		if !g.meta.AppliesTo.applies(root, languages) {
+			logVerbose("skipping guideline %s: applies to %s", g.name, g.meta.AppliesTo)
+			continue
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+// keepGuidelines returns the guidelines of selected that are also in keep,
+// in selected's order.
+func keepGuidelines(selected, keep []guideline) []guideline {
+	names := make(map[string]bool, len(keep))
+	for _, g := range keep {
+		names[g.name] = true
+	}
+	var out []guideline
+	for _, g := range selected {
+		if names[g.name] {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+func handleGuidelinesCommand(configDir string, args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("usage: beet guidelines list")
+	}
+	fs := flag.NewFlagSet("guidelines list", flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	guidelines, err := loadGuidelines(configDir)
+	if err != nil {
+		return err
+	}
+	return listGuidelines(os.Stdout, guidelines)
+}
+
+func listGuidelines(w io.Writer, guidelines []guideline) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "NAME\tPRIORITY\tTAGS\tAPPLIES TO\tSOURCE"); err != nil {
+		return err
+	}
+	for _, g := range guidelines {
+		name := g.name
+		if g.meta.Title != "" {
+			name += " (" + g.meta.Title + ")"
+		}
+		tags := strings.Join(g.meta.Tags, ",")
+		if tags == "" {
+			tags = "-"
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", name, g.meta.Priority, tags, g.meta.AppliesTo, g.source); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// guidelineSelector picks the guidelines an output receives. Names may be
+// globs; a nil Include keeps every guideline before Exclude is applied.
+type guidelineSelector struct {
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+	Tags    []string `yaml:"tags,omitempty"`
+}
+
+// UnmarshalYAML accepts `guidelines: [a, b]` and `guidelines: a` as
+// shorthand for an include list, alongside the include/exclude mapping.
+func (s *guidelineSelector) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		s.Include = []string{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		s.Include = []string{}
+		return node.Decode(&s.Include)
+	case yaml.MappingNode:
+		type plain guidelineSelector
+		return node.Decode((*plain)(s))
+	}
+	return fmt.Errorf("line %d: guidelines must be a list of names or an include/exclude mapping", node.Line)
+}
+
+func (s *guidelineSelector) selectFrom(guidelines []guideline) ([]guideline, error) {
+	if s == nil {
+		return guidelines, nil
+	}
+	for _, pattern := range append(append([]string(nil), s.Include...), s.Exclude...) {
+		if _, err := path.Match(guidelinePattern(pattern), ""); err != nil {
+			return nil, fmt.Errorf("guideline pattern %q: %w", pattern, err)
+		}
+	}
+	for _, pattern := range s.Include {
+		if !isGlob(pattern) && !anyGuidelineMatches(guidelines, pattern) {
+			return nil, fmt.Errorf("unknown guideline %s", pattern)
+		}
+	}
+
+	var out []guideline
+	for _, g := range guidelines {
+		if s.Include != nil && !guidelineMatches(g, s.Include) {
+			continue
+		}
+		if guidelineMatches(g, s.Exclude) {
+			continue
+		}
+		if len(s.Tags) > 0 && !g.meta.hasAnyTag(s.Tags) {
+			continue
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+func guidelinePattern(pattern string) string {
+	return strings.TrimSuffix(strings.TrimSpace(pattern), ".md")
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+func guidelineMatches(g guideline, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(guidelinePattern(pattern), g.name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func anyGuidelineMatches(guidelines []guideline, pattern string) bool {
+	for _, g := range guidelines {
+		if guidelineMatches(g, []string{pattern}) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+import "gopkg.in/yaml.v3"
+
+func guidelineNames(gs []guideline) []string {
+	var out []string
+	for _, g := range gs {
+		out = append(out, g.name)
+	}
+	return out
+}
+
+func TestGuidelineSelectorYAMLForms(t *testing.T) {
+	cases := map[string]guidelineSelector{
+		"guidelines: security\n":                        {Include: []string{"security"}},
+		"guidelines: [security, go-style]\n":            {Include: []string{"security", "go-style"}},
+		"guidelines: []\n":                              {Include: []string{}},
+		"guidelines:\n  exclude: [\"long-*\"]\n":        {Exclude: []string{"long-*"}},
+		"guidelines:\n  include: [a]\n  exclude: [b]\n": {Include: []string{"a"}, Exclude: []string{"b"}},
+	}
+	for input, want := range cases {
+		var out packOutput
+		if err := yaml.Unmarshal([]byte(input), &out); err != nil {
+			t.Fatalf("unmarshal %q: %v", input, err)
+		}
+		if out.Guidelines == nil || !reflect.DeepEqual(*out.Guidelines, want) {
+			t.Fatalf("unmarshal %q = %+v, want %+v", input, out.Guidelines, want)
+		}
+	}
+}
+
+func TestGuidelineSelectorSelects(t *testing.T) {
+	all := []guideline{{name: "go-style"}, {name: "long-rulebook"}, {name: "principles"}, {name: "security"}}
+	cases := []struct {
+		sel  *guidelineSelector
+		want []string
+	}{
+		{nil, []string{"go-style", "long-rulebook", "principles", "security"}},
+		{&guidelineSelector{Include: []string{"security", "principles.md"}}, []string{"principles", "security"}},
+		{&guidelineSelector{Include: []string{"*-*"}}, []string{"go-style", "long-rulebook"}},
+		{&guidelineSelector{Exclude: []string{"long-*"}}, []string{"go-style", "principles", "security"}},
+		{&guidelineSelector{Include: []string{}}, nil},
+	}
+	for _, tc := range cases {
+		got, err := tc.sel.selectFrom(all)
+		if err != nil {
+			t.Fatalf("selectFrom(%+v): %v", tc.sel, err)
+		}
+		if !reflect.DeepEqual(guidelineNames(got), tc.want) {
+			t.Fatalf("selectFrom(%+v) = %v, want %v", tc.sel, guidelineNames(got), tc.want)
+		}
+	}
+
+	if _, err := (&guidelineSelector{Include: []string{"securty"}}).selectFrom(all); err == nil || !strings.Contains(err.Error(), "unknown guideline securty") {
+		t.Fatalf("expected unknown guideline error, got %v", err)
+	}
+	if _, err := (&guidelineSelector{Exclude: []string{"["}}).selectFrom(all); err == nil {
+		t.Fatalf("expected bad pattern error")
+	}
+}
+
+func TestHandleGenerateSelectsGuidelinesPerOutput(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "{{guidelines}}\n")
+	writeConfigFile(t, configDir, "guidelines/principles.md", "SHORT PRINCIPLES")
+	writeConfigFile(t, configDir, "guidelines/security.md", "FULL SECURITY RULES")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: WORK.md\n    template: t.md\n    guidelines: [principles]\n  - file: RULES.md\n    template: t.md\n")
+	t.Chdir(t.TempDir())
+
+	if err := handleGenerate(configDir, []string{"-p", "p", "ship"}); err != nil {
+		t.Fatalf("handleGenerate: %v", err)
+	}
+
+	work, _ := os.ReadFile("WORK.md")
+	if !strings.Contains(string(work), "SHORT PRINCIPLES") || strings.Contains(string(work), "FULL SECURITY RULES") {
+		t.Fatalf("WORK.md guidelines not filtered:\n%s", work)
+	}
+	rules, _ := os.ReadFile("RULES.md")
+	if !strings.Contains(string(rules), "SHORT PRINCIPLES") || !strings.Contains(string(rules), "FULL SECURITY RULES") {
+		t.Fatalf("RULES.md should carry every guideline:\n%s", rules)
+	}
+}
+
+func TestParseGuidelineFrontMatter(t *testing.T) {
+	text := "---\ntitle: Go style\ntags: [Go, Style]\npriority: 5\napplies_to:\n  languages: [go]\n  paths: [go.mod]\n---\n\nUse gofmt.\n"
+	g, err := parseGuideline("go-style", "/cfg/guidelines/go-style.md", text)
+	if err != nil {
+		t.Fatalf("parseGuideline: %v", err)
+	}
+	if g.content != "Use gofmt.\n" {
+		t.Fatalf("content = %q", g.content)
+	}
+	want := guidelineMeta{Title: "Go style", Tags: []string{"go", "style"}, Priority: 5, AppliesTo: guidelineScope{Languages: []string{"go"}, Paths: []string{"go.mod"}}}
+	if !reflect.DeepEqual(g.meta, want) {
+		t.Fatalf("meta = %+v, want %+v", g.meta, want)
+	}
+
+	plain, err := parseGuideline("plain", "", "no front matter\n")
+	if err != nil || plain.content != "no front matter\n" {
+		t.Fatalf("plain guideline = %+v, %v", plain, err)
+	}
+
+	if _, err := parseGuideline("typo", "", "---\nprioriy: 1\n---\nbody\n"); err == nil || !strings.Contains(err.Error(), "parse guideline typo front matter") {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestLoadGuidelinesOrdersByPriority(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(dir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, dir, "guidelines/a.md", "a")
+	writeConfigFile(t, dir, "guidelines/b.md", "---\npriority: 10\n---\nb")
+	writeConfigFile(t, dir, "guidelines/c.md", "---\npriority: -1\n---\nc")
+	writeConfigFile(t, dir, "guidelines/d.md", "d")
+
+	got, err := loadGuidelines(dir)
+	if err != nil {
+		t.Fatalf("loadGuidelines: %v", err)
+	}
+	if names := guidelineNames(got); !reflect.DeepEqual(names, []string{"b", "a", "d", "c"}) {
+		t.Fatalf("order = %v", names)
+	}
+}
+
+func TestHandleGenerateIncludesInapplicableGuidelineByName(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "{{guidelines}}\n")
+	writeConfigFile(t, configDir, "guidelines/go-style.md", "---\napplies_to:\n  languages: [go]\n---\nGO STYLE")
+	writeConfigFile(t, configDir, "guidelines/principles.md", "PRINCIPLES")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: RULES.md\n    template: t.md\n    guidelines: [go-style, principles]\n")
+	writeConfigFile(t, configDir, "packs/typo.yaml", "outputs:\n  - file: RULES.md\n    template: t.md\n    guidelines: [go-styel]\n")
+	root := t.TempDir()
+	writeConfigFile(t, root, "package.json", "{}")
+	t.Chdir(root)
+
+	if err := handleGenerate(configDir, []string{"-p", "p", "ship"}); err != nil {
+		t.Fatalf("handleGenerate: %v", err)
+	}
+	rules, _ := os.ReadFile("RULES.md")
+	if strings.Contains(string(rules), "GO STYLE") || !strings.Contains(string(rules), "PRINCIPLES") {
+		t.Fatalf("RULES.md:\n%s", rules)
+	}
+	if err := handleGenerate(configDir, []string{"-p", "typo", "ship"}); err == nil || !strings.Contains(err.Error(), "unknown guideline go-styel") {
+		t.Fatalf("got %v, want an unknown guideline error", err)
+	}
+}
+
+func TestApplicableGuidelines(t *testing.T) {
+	root := t.TempDir()
+	writeConfigFile(t, root, "go.mod", "module x\n")
+
+	gs := []guideline{
+		{name: "any"},
+		{name: "go-path", meta: guidelineMeta{AppliesTo: guidelineScope{Paths: []string{"go.mod"}}}},
+		{name: "node-path", meta: guidelineMeta{AppliesTo: guidelineScope{Paths: []string{"package.json"}}}},
+		{name: "ts", meta: guidelineMeta{AppliesTo: guidelineScope{Languages: []string{"TypeScript"}}}},
+	}
+
+	if names := guidelineNames(applicableGuidelines(gs, root, nil)); !reflect.DeepEqual(names, []string{"any", "go-path", "ts"}) {
+		t.Fatalf("unknown languages = %v", names)
+	}
+	if names := guidelineNames(applicableGuidelines(gs, root, []string{"go"})); !reflect.DeepEqual(names, []string{"any", "go-path"}) {
+		t.Fatalf("go repo = %v", names)
+	}
+}
+
+func TestGuidelineSelectorFiltersByTag(t *testing.T) {
+	gs := []guideline{
+		{name: "a", meta: guidelineMeta{Tags: []string{"security"}}},
+		{name: "b", meta: guidelineMeta{Tags: []string{"style"}}},
+		{name: "c"},
+	}
+	got, err := (&guidelineSelector{Tags: []string{"Security"}}).selectFrom(gs)
+	if err != nil || !reflect.DeepEqual(guidelineNames(got), []string{"a"}) {
+		t.Fatalf("tag selection = %v, %v", guidelineNames(got), err)
+	}
+}
+
+func TestListGuidelines(t *testing.T) {
+	gs := []guideline{
+		{name: "go-style", source: "/p/.beet/guidelines/go-style.md", meta: guidelineMeta{Title: "Go", Tags: []string{"go"}, Priority: 2, AppliesTo: guidelineScope{Languages: []string{"go"}}}},
+		{name: "principles", source: "/u/guidelines/principles.md"},
+	}
+	var b strings.Builder
+	if err := listGuidelines(&b, gs); err != nil {
+		t.Fatalf("listGuidelines: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "NAME") {
+		t.Fatalf("list output:\n%s", b.String())
+	}
+	for _, want := range []string{"go-style (Go)", "languages go", "/p/.beet/guidelines/go-style.md"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("line %q missing %q", lines[1], want)
+		}
+	}
+	if fields := strings.Fields(lines[2]); !reflect.DeepEqual(fields, []string{"principles", "0", "-", "all", "/u/guidelines/principles.md"}) {
+		t.Fatalf("line fields = %v", fields)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+import "gopkg.in/yaml.v3"
+
+const (
+	initSourceDefaults = "defaults"
+	initSourceUser     = "user"
+	gitignoreMarker    = "# beet generated outputs"
+)
+
+var projectConfigSubdirs = []string{templatesDirName, guidelinesDirName, packsDirName, partialsDirName}
+
+type initOptions struct {
+	from      string
+	link      bool
+	pack      string
+	gitignore bool
+}
+
+func handleInit(configDir string, args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	from := fs.String("from", "", "copy config from the bundled defaults or your user config (defaults|user)")
+	link := fs.Bool("link", false, "symlink templates, guidelines, packs and partials from your user config")
+	pack := fs.String("p", "", "pack to pin as the project default")
+	packLong := fs.String("pack", "", "pack to pin as the project default")
+	gitignore := fs.Bool("gitignore", false, "add the pinned pack's outputs to .gitignore")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if len(fs.Args()) > 0 {
+		return fmt.Errorf("usage: beet init [--from defaults|user] [--link] [-p pack] [--gitignore]")
+	}
+
+	root, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getwd: %w", err)
+	}
+
+	return initProject(os.Stdout, configDir, root, initOptions{
+		from:      *from,
+		link:      *link,
+		pack:      firstNonEmpty(*pack, *packLong),
+		gitignore: *gitignore,
+	})
+}
+
+func initProject(w io.Writer, configDir, root string, opts initOptions) error {
+	dir := filepath.Join(root, defaultConfigFolder)
+	if sameFileKey(dir) == sameFileKey(configDir) {
+		return fmt.Errorf("%s is your user config dir; run beet init inside a repository", dir)
+	}
+
+	source := opts.from
+	if source == "" {
+		source = initSourceDefaults
+		if opts.link {
+			source = initSourceUser
+		}
+	}
+	if source != initSourceDefaults && source != initSourceUser {
+		return fmt.Errorf("unknown --from %q (want defaults or user)", opts.from)
+	}
+	if opts.link && source != initSourceUser {
+		return fmt.Errorf("--link only works with --from user")
+	}
+
+	var added int
+	var err error
+	switch {
+	case opts.link:
+		added, err = linkUserConfig(configDir, dir)
+	case source == initSourceUser:
+		added, err = copyUserConfig(configDir, dir)
+	default:
+		if err = ensureConfigStructure(dir); err == nil {
+			added, err = copyTree(embeddedDefaults, "defaults", dir)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	logVerbose("init added %d entries to %s from %s", added, dir, source)
+
+	settings, err := writeProjectSettings(configDir, dir, opts.pack)
+	if err != nil {
+		return err
+	}
+
+	if opts.gitignore {
+		p, err := loadPack(configDir, settings.Pack)
+		if err != nil {
+			return err
+		}
+		var files []string
+		for _, out := range p.Outputs {
+			files = append(files, out.File)
+		}
+		if err := appendGitignore(filepath.Join(root, ".gitignore"), files); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "initialized %s (%d added from %s, default pack %s)\n", dir, added, source, settings.Pack)
+	return err
+}
+
+func copyUserConfig(configDir, dir string) (int, error) {
+	if err := ensureConfigStructure(dir); err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, sub := range projectConfigSubdirs {
+		if _, err := os.Stat(filepath.Join(configDir, sub)); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		n, err := copyTree(os.DirFS(configDir), sub, filepath.Join(dir, sub))
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func linkUserConfig(configDir, dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, fmt.Errorf("create project config dir: %w", err)
+	}
+	linked := 0
+	for _, sub := range projectConfigSubdirs {
+		target := filepath.Join(dir, sub)
+		if _, err := os.Lstat(target); err == nil {
+			continue
+		}
+		if err := os.Symlink(filepath.Join(configDir, sub), target); err != nil {
+			return linked, fmt.Errorf("link %s: %w", sub, err)
+		}
+		linked++
+	}
+	return linked, nil
+}
+
+// writeProjectSettings pins pack in .beet/config.yaml. An existing pin is kept
+// unless a pack is named explicitly.
+func writeProjectSettings(configDir, dir, pack string) (projectSettings, error) {
+	path := filepath.Join(dir, projectSettingsFilename)
+
+	var settings projectSettings
+	if data, err := os.ReadFile(path); err == nil {
+		if err := yaml.Unmarshal(data, &settings); err != nil {
+			return projectSettings{}, fmt.Errorf("parse %s: %w", path, err)
+		}
+		if pack == "" && settings.Pack != "" {
+			return settings, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return projectSettings{}, fmt.Errorf("read project settings: %w", err)
+	}
+
+	settings.Pack = normalizePackName(pack)
+	if _, err := loadPack(configDir, settings.Pack); err != nil {
+		return projectSettings{}, err
+	}
+
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return projectSettings{}, fmt.Errorf("encode project settings: %w", err)
+	}
+	// Like the manifest, the settings are committed, so they get the usual
+	// file mode rather than writeFileAtomic's private one.
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return projectSettings{}, fmt.Errorf("write project settings: %w", err)
+	}
+	return settings, nil
+}
+
+func appendGitignore(path string, entries []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read .gitignore: %w", err)
+	}
+
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		existing[strings.TrimSpace(line)] = true
+	}
+
+	var missing []string
+	for _, entry := range entries {
+		entry = "/" + filepath.ToSlash(entry)
+		if !existing[entry] {
+			missing = append(missing, entry)
+			existing[entry] = true
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	content := string(data)
+	if content != "" {
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += "\n"
+	}
+	if !existing[gitignoreMarker] {
+		content += gitignoreMarker + "\n"
+	}
+	content += strings.Join(missing, "\n") + "\n"
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write .gitignore: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupInitDirs(t *testing.T) (userDir, root string) {
+	t.Helper()
+	userDir = filepath.Join(t.TempDir(), "user")
+	if err := ensureConfigStructure(userDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	if err := bootstrapDefaults(userDir); err != nil {
+		t.Fatalf("bootstrapDefaults: %v", err)
+	}
+	root = t.TempDir()
+	t.Chdir(root)
+	return userDir, root
+}
+
+func TestInitProjectCopiesDefaultsAndPinsPack(t *testing.T) {
+	userDir, root := setupInitDirs(t)
+
+	var out strings.Builder
+	if err := initProject(&out, userDir, root, initOptions{pack: "extended"}); err != nil {
+		t.Fatalf("initProject: %v", err)
+	}
+
+	dir := filepath.Join(root, defaultConfigFolder)
+	for _, rel := range []string{"templates/prd.md", "packs/default.yaml", "partials/guidelines.md"} {
+		if _, err := os.Stat(filepath.Join(dir, rel)); err != nil {
+			t.Fatalf("expected %s: %v", rel, err)
+		}
+	}
+	settings, err := os.ReadFile(filepath.Join(dir, projectSettingsFilename))
+	if err != nil || string(settings) != "pack: extended.yaml\n" {
+		t.Fatalf("config.yaml = %q, %v", settings, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, projectSettingsFilename)); err != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("config.yaml mode: %v, %v", info, err)
+	}
+	if !strings.Contains(out.String(), "default pack extended.yaml") {
+		t.Fatalf("init output = %q", out.String())
+	}
+
+	got, path, err := loadProjectSettings(userDir)
+	if err != nil || got.Pack != "extended.yaml" || path == "" {
+		t.Fatalf("loadProjectSettings = %+v, %q, %v", got, path, err)
+	}
+}
+
+func TestInitProjectIsIdempotent(t *testing.T) {
+	userDir, root := setupInitDirs(t)
+	dir := filepath.Join(root, defaultConfigFolder)
+
+	if err := initProject(&strings.Builder{}, userDir, root, initOptions{pack: "comprehensive"}); err != nil {
+		t.Fatalf("first init: %v", err)
+	}
+	custom := filepath.Join(dir, "templates", "default.md")
+	if err := os.WriteFile(custom, []byte("team template"), 0o644); err != nil {
+		t.Fatalf("customize: %v", err)
+	}
+
+	var out strings.Builder
+	if err := initProject(&out, userDir, root, initOptions{}); err != nil {
+		t.Fatalf("second init: %v", err)
+	}
+	if !strings.Contains(out.String(), "(0 added from defaults, default pack comprehensive.yaml)") {
+		t.Fatalf("second init output = %q", out.String())
+	}
+	if data, _ := os.ReadFile(custom); string(data) != "team template" {
+		t.Fatalf("customized template overwritten: %q", data)
+	}
+}
+
+func TestInitProjectFromUserConfig(t *testing.T) {
+	userDir, root := setupInitDirs(t)
+	writeConfigFile(t, userDir, "templates/mine.md", "mine")
+
+	if err := initProject(&strings.Builder{}, userDir, root, initOptions{from: initSourceUser}); err != nil {
+		t.Fatalf("initProject: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, defaultConfigFolder, "templates", "mine.md"))
+	if err != nil || string(data) != "mine" {
+		t.Fatalf("copied template = %q, %v", data, err)
+	}
+}
+
+func TestInitProjectLinksUserConfig(t *testing.T) {
+	userDir, root := setupInitDirs(t)
+
+	if err := initProject(&strings.Builder{}, userDir, root, initOptions{link: true}); err != nil {
+		t.Fatalf("initProject: %v", err)
+	}
+	target, err := os.Readlink(filepath.Join(root, defaultConfigFolder, templatesDirName))
+	if err != nil || target != filepath.Join(userDir, templatesDirName) {
+		t.Fatalf("templates link = %q, %v", target, err)
+	}
+
+	if err := initProject(&strings.Builder{}, userDir, root, initOptions{link: true, from: initSourceDefaults}); err == nil {
+		t.Fatalf("expected error combining --link with --from defaults")
+	}
+}
+
+func TestInitProjectRejectsUnknownPack(t *testing.T) {
+	userDir, root := setupInitDirs(t)
+	if err := initProject(&strings.Builder{}, userDir, root, initOptions{pack: "missing"}); err == nil {
+		t.Fatalf("expected error for unknown pack")
+	}
+}
+
+func TestInitProjectWritesGitignore(t *testing.T) {
+	userDir, root := setupInitDirs(t)
+	gitignore := filepath.Join(root, ".gitignore")
+	if err := os.WriteFile(gitignore, []byte("bin/\n/agents.md"), 0o644); err != nil {
+		t.Fatalf("write .gitignore: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := initProject(&strings.Builder{}, userDir, root, initOptions{gitignore: true}); err != nil {
+			t.Fatalf("initProject #%d: %v", i, err)
+		}
+	}
+
+	data, err := os.ReadFile(gitignore)
+	if err != nil {
+		t.Fatalf("read .gitignore: %v", err)
+	}
+	want := "bin/\n/agents.md\n\n" + gitignoreMarker + "\n/WORK_PROMPT.md\n"
+	if string(data) != want {
+		t.Fatalf(".gitignore = %q, want %q", data, want)
+	}
+}
+
+func TestHandleGenerateUsesPinnedPack(t *testing.T) {
+	userDir, root := setupInitDirs(t)
+	if err := initProject(&strings.Builder{}, userDir, root, initOptions{pack: "extended"}); err != nil {
+		t.Fatalf("initProject: %v", err)
+	}
+
+	if err := handleGenerate(userDir, []string{"ship it"}); err != nil {
+		t.Fatalf("handleGenerate: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "PRD.md")); err != nil {
+		t.Fatalf("expected PRD.md from pinned extended pack: %v", err)
+	}
+}
+
+func TestInitProjectRefusesUserConfigDir(t *testing.T) {
+	home := t.TempDir()
+	userDir := filepath.Join(home, defaultConfigFolder)
+	if err := ensureConfigStructure(userDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	if err := initProject(&strings.Builder{}, userDir, home, initOptions{}); err == nil {
+		t.Fatalf("expected error when initializing inside the user config dir")
+	}
+}
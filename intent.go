@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+import "gopkg.in/yaml.v3"
+
+var knownPlaceholders = []string{
+	"intent",
+	"background",
+	"goals",
+	"requirements",
+	"assumptions",
+	"constraints",
+	"risks",
+	"deliverables",
+	"acceptance_criteria",
+	"guidelines",
+	"open_questions",
+}
+
+var sectionAliases = map[string]string{
+	"context":            "background",
+	"goal":               "goals",
+	"objectives":         "goals",
+	"requirement":        "requirements",
+	"assumption":         "assumptions",
+	"constraint":         "constraints",
+	"risk":               "risks",
+	"deliverable":        "deliverables",
+	"acceptance":         "acceptance_criteria",
+	"definition_of_done": "acceptance_criteria",
+	"questions":          "open_questions",
+}
+
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+type intentDocument struct {
+	full   string
+	body   string
+	fields placeholderValues
+}
+
+func parseIntentDocument(text string) (intentDocument, error) {
+	doc := intentDocument{fields: placeholderValues{}}
+
+	content, frontMatter, err := splitFrontMatter(text)
+	if err != nil {
+		return intentDocument{}, err
+	}
+	for name, value := range frontMatter {
+		doc.fields[name] = value
+	}
+
+	var body []string
+	var section []string
+	sectionName := ""
+	sectionLevel := 0
+	var fence codeFence
+
+	flush := func() {
+		if sectionName == "" {
+			return
+		}
+		value := strings.TrimSpace(strings.Join(section, "\n"))
+		if existing := doc.fields[sectionName]; existing != "" && value != "" {
+			value = existing + "\n\n" + value
+		} else if existing != "" {
+			value = existing
+		}
+		doc.fields[sectionName] = value
+		sectionName = ""
+		section = nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if !fence.update(line) {
+			if m := headingPattern.FindStringSubmatch(line); m != nil {
+				level := len(m[1])
+				if sectionName == "" || level <= sectionLevel {
+					flush()
+					if name, ok := sectionPlaceholder(m[2]); ok {
+						sectionName = name
+						sectionLevel = level
+						continue
+					}
+				}
+			}
+		}
+
+		if sectionName != "" {
+			section = append(section, line)
+		} else {
+			body = append(body, line)
+		}
+	}
+	flush()
+
+	doc.full = strings.TrimSpace(content)
+	doc.body = strings.TrimSpace(strings.Join(body, "\n"))
+	if preface := doc.fields["intent"]; preface != "" {
+		doc.body = strings.TrimSpace(preface + "\n\n" + doc.body)
+		delete(doc.fields, "intent")
+	}
+	delete(doc.fields, "guidelines")
+
+	return doc, nil
+}
+
+func sectionPlaceholder(heading string) (string, bool) {
+	name := normalizePlaceholderName(heading)
+	if alias, ok := sectionAliases[name]; ok {
+		name = alias
+	}
+	if name == "intent" || name == "guidelines" {
+		return "", false
+	}
+	for _, known := range knownPlaceholders {
+		if known == name {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// cutFrontMatter splits a leading `---` delimited YAML block from text. The
+// returned body has CRLF line endings normalized either way.
+func cutFrontMatter(text string) (front, body string, ok bool) {
+	normalized := strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return "", normalized, false
+	}
+
+	rest := normalized[len("---\n"):]
+	offset := 0
+	for _, line := range strings.SplitAfter(rest, "\n") {
+		if strings.TrimRight(line, "\n") == "---" {
+			return rest[:offset], rest[offset+len(line):], true
+		}
+		offset += len(line)
+	}
+	return "", normalized, false
+}
+
+// joinIntentParts joins intent sources with blank lines. Front matter only
+// counts at the start of the text, so when a later source has some, every
+// source's front matter is merged into one block in front, later sources
+// winning per field.
+func joinIntentParts(parts []string) (string, error) {
+	later := false
+	for _, part := range parts[1:] {
+		if _, _, ok := cutFrontMatter(part); ok {
+			later = true
+		}
+	}
+	if !later {
+		return strings.Join(parts, "\n\n"), nil
+	}
+
+	merged := make(map[string]interface{})
+	bodies := make([]string, 0, len(parts))
+	for _, part := range parts {
+		front, body, ok := cutFrontMatter(part)
+		if ok {
+			var raw map[string]interface{}
+			if err := yaml.Unmarshal([]byte(front), &raw); err != nil {
+				return "", fmt.Errorf("parse intent front matter: %w", err)
+			}
+			for key, value := range raw {
+				merged[frontMatterName(key)] = value
+			}
+		}
+		if body = strings.TrimSpace(body); body != "" {
+			bodies = append(bodies, body)
+		}
+	}
+	front, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", fmt.Errorf("encode intent front matter: %w", err)
+	}
+	return "---\n" + string(front) + "---\n" + strings.Join(bodies, "\n\n"), nil
+}
+
+// frontMatterName resolves a front matter key to its placeholder name, or
+// returns it as is when it names none so splitFrontMatter can report it.
+func frontMatterName(key string) string {
+	name := normalizePlaceholderName(key)
+	if alias, ok := sectionAliases[name]; ok {
+		name = alias
+	}
+	if !validPlaceholderName(name) {
+		return key
+	}
+	return name
+}
+
+func splitFrontMatter(text string) (string, placeholderValues, error) {
+	front, body, ok := cutFrontMatter(text)
+	if !ok {
+		return body, nil, nil
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal([]byte(front), &raw); err != nil {
+		return "", nil, fmt.Errorf("parse intent front matter: %w", err)
+	}
+
+	values := make(placeholderValues, len(raw))
+	for key, value := range raw {
+		name := frontMatterName(key)
+		if !validPlaceholderName(name) {
+			return "", nil, fmt.Errorf("intent front matter: invalid placeholder name %q", key)
+		}
+		if _, ok := values[name]; ok {
+			return "", nil, fmt.Errorf("intent front matter: duplicate field %q", name)
+		}
+		values[name] = formatPlaceholderValue(value)
+	}
+
+	return body, values, nil
+}
+
+func intentPlaceholders(doc intentDocument) placeholderValues {
+	values := placeholderValues{
+		"intent":      doc.body,
+		"intent_full": doc.full,
+	}
+	return values.merge(doc.fields)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIntentDocumentSplitsSections(t *testing.T) {
+	text := strings.Join([]string{
+		"# Checkout revamp",
+		"Rebuild the checkout flow.",
+		"",
+		"## Goals",
+		"- faster checkout",
+		"### Stretch",
+		"- one-click",
+		"",
+		"## Notes",
+		"keep the old flow behind a flag",
+		"",
+		"## Acceptance Criteria",
+		"- p95 under 2s",
+		"```",
+		"## Risks",
+		"```",
+	}, "\n")
+
+	doc, err := parseIntentDocument(text)
+	if err != nil {
+		t.Fatalf("parseIntentDocument returned error: %v", err)
+	}
+
+	wantBody := "# Checkout revamp\nRebuild the checkout flow.\n\n## Notes\nkeep the old flow behind a flag"
+	if doc.body != wantBody {
+		t.Fatalf("body = %q, want %q", doc.body, wantBody)
+	}
+	if doc.full != text {
+		t.Fatalf("full = %q, want original text", doc.full)
+	}
+
+	want := placeholderValues{
+		"goals":               "- faster checkout\n### Stretch\n- one-click",
+		"acceptance_criteria": "- p95 under 2s\n```\n## Risks\n```",
+	}
+	if !reflect.DeepEqual(doc.fields, want) {
+		t.Fatalf("fields = %v, want %v", doc.fields, want)
+	}
+}
+
+func TestParseIntentDocumentFrontMatter(t *testing.T) {
+	text := "---\ngoals:\n  - ship\n  - measure\naudience: PMs\nintent: Preface line\n---\nBody text\n\n## Risks\nnone known\n"
+
+	doc, err := parseIntentDocument(text)
+	if err != nil {
+		t.Fatalf("parseIntentDocument returned error: %v", err)
+	}
+
+	if doc.body != "Preface line\n\nBody text" {
+		t.Fatalf("body = %q", doc.body)
+	}
+	if doc.full != "Body text\n\n## Risks\nnone known" {
+		t.Fatalf("full = %q", doc.full)
+	}
+	if doc.fields["audience"] != "PMs" {
+		t.Fatalf("audience = %q, want PMs", doc.fields["audience"])
+	}
+	if doc.fields["risks"] != "none known" {
+		t.Fatalf("risks = %q, want none known", doc.fields["risks"])
+	}
+	if _, ok := doc.fields["intent"]; ok {
+		t.Fatalf("intent should not remain a field: %v", doc.fields)
+	}
+}
+
+func TestParseIntentDocumentRejectsBadFrontMatter(t *testing.T) {
+	for _, text := range []string{
+		"---\ngoals: [unclosed\n---\nbody\n",
+		"---\ngoals: a\nobjectives: b\n---\nbody\n",
+	} {
+		if _, err := parseIntentDocument(text); err == nil {
+			t.Fatalf("expected front matter error for %q", text)
+		}
+	}
+}
+
+func TestParseIntentDocumentPlainText(t *testing.T) {
+	doc, err := parseIntentDocument("  just ship it \n")
+	if err != nil {
+		t.Fatalf("parseIntentDocument returned error: %v", err)
+	}
+	if doc.body != "just ship it" || len(doc.fields) != 0 {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+}
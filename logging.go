@@ -9,6 +9,7 @@ import (
 var (
 	verboseEnabled bool
 	verboseLogger  = log.New(io.Discard, "beet [verbose] ", log.LstdFlags)
+	warningLogger  = log.New(os.Stderr, "beet [warning] ", 0)
 )
 
 func configureVerboseLogging(enabled bool) {
@@ -26,3 +27,7 @@ func logVerbose(format string, args ...interface{}) {
 	}
 	verboseLogger.Printf(format, args...)
 }
+
+func logWarning(format string, args ...interface{}) {
+	warningLogger.Printf(format, args...)
+}
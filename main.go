@@ -13,23 +13,35 @@ const agentsFilename = "agents.md"
 type guideline struct {
 	name    string
 	content string
+	source  string
+	meta    guidelineMeta
 }
 
-func renderTemplate(template, intent, guidelines string) string {
-	rendered := strings.ReplaceAll(template, "{{intent}}", strings.TrimSpace(intent))
-	return strings.ReplaceAll(rendered, "{{guidelines}}", guidelines)
+func renderTemplate(template, intent, guidelines string) (string, error) {
+	rendered, _, err := renderTemplateText(defaultTemplateName, template, basePlaceholders(intent, guidelines), nil)
+	return rendered, err
 }
 
-func buildWorkPrompt(templateName, template string, guidelines []guideline, intent string) string {
-	guidelineText := formatGuidelines(guidelines)
-	body := renderTemplate(template, intent, guidelineText)
+func buildWorkPrompt(templateName, template string, guidelines []guideline, intent string) (string, error) {
+	prompt, _, err := buildPrompt(templateName, template, basePlaceholders(intent, formatGuidelines(guidelines)), nil)
+	return prompt, err
+}
+
+func buildPrompt(templateName, template string, values placeholderValues, load partialLoader) (string, []string, error) {
+	filename := normalizeTemplateName(templateName)
+	label := strings.TrimSuffix(filename, filepath.Ext(filename))
+
+	body, empty, err := renderTemplateText(filename, template, values, load)
+	if err != nil {
+		return "", nil, err
+	}
 
 	var b strings.Builder
 	b.WriteString(internalInstruction)
 	b.WriteString("\n\n")
-	b.WriteString(fmt.Sprintf("Template: %s\n", templateName))
+	b.WriteString(fmt.Sprintf("Template: %s\n", label))
 	b.WriteString(body)
-	return b.String()
+	return b.String(), empty, nil
 }
 
 func formatGuidelines(guidelines []guideline) string {
@@ -46,8 +58,6 @@ func formatGuidelines(guidelines []guideline) string {
 }
 
 func buildWorkPromptContent(configDir, templateName, intent string) (string, error) {
-	normalized := normalizeTemplateName(templateName)
-
 	template, err := loadTemplate(configDir, templateName)
 	if err != nil {
 		return "", err
@@ -58,8 +68,9 @@ func buildWorkPromptContent(configDir, templateName, intent string) (string, err
 		return "", err
 	}
 
-	label := strings.TrimSuffix(normalized, filepath.Ext(normalized))
-	return buildWorkPrompt(label, template, guidelines, intent), nil
+	values := basePlaceholders(intent, formatGuidelines(guidelines))
+	prompt, _, err := buildPrompt(templateName, template, values, partialsFrom(configDir))
+	return prompt, err
 }
 
 func writeWorkPrompt(configDir, templateName, intent, outputPath string) error {
@@ -110,22 +121,3 @@ func writeAgents(configDir, outputPath string, force bool) error {
 
 	return nil
 }
-
-func writeRenderedOutput(path string, content string, forceAgents bool) error {
-	if strings.EqualFold(filepath.Base(path), agentsFilename) {
-		if _, err := os.Stat(path); err == nil && !forceAgents {
-			return nil
-		}
-	}
-
-	if dir := filepath.Dir(path); dir != "." && dir != "" {
-		if err := os.MkdirAll(dir, 0o755); err != nil {
-			return fmt.Errorf("create output dir: %w", err)
-		}
-	}
-
-	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
-		return fmt.Errorf("write %s: %w", path, err)
-	}
-	return nil
-}
@@ -9,7 +9,10 @@ import (
 
 func TestRenderTemplate(t *testing.T) {
 	template := "Intent: {{intent}}\nRules: {{guidelines}}\n"
-	out := renderTemplate(template, " ship feature X ", "rule1")
+	out, err := renderTemplate(template, " ship feature X ", "rule1")
+	if err != nil {
+		t.Fatalf("renderTemplate returned error: %v", err)
+	}
 	want := "Intent: ship feature X\nRules: rule1\n"
 	if out != want {
 		t.Fatalf("renderTemplate mismatch\nwant: %q\ngot:  %q", want, out)
@@ -22,7 +25,10 @@ func TestBuildWorkPromptInjectsGuidelines(t *testing.T) {
 		{name: "alpha", content: "keep it short"},
 		{name: "beta", content: "ship it"},
 	}
-	out := buildWorkPrompt("default", template, guides, "do the thing")
+	out, err := buildWorkPrompt("default", template, guides, "do the thing")
+	if err != nil {
+		t.Fatalf("buildWorkPrompt returned error: %v", err)
+	}
 	if !containsAll(out, []string{"do the thing", "keep it short", "ship it", "Internal instruction"}) {
 		t.Fatalf("work prompt missing expected content: %s", out)
 	}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strings"
+)
+
+import "gopkg.in/yaml.v3"
+
+const (
+	manifestFilename = "manifest.json"
+	manifestVersion  = 1
+)
+
+// manifest records what produced the outputs in a directory: the pack, the
+// options and intent to replay, and a hash of every input of every output.
+// It holds no timestamps so regenerating unchanged inputs leaves it as is.
+// Intent is empty when the intent did not come from the command line.
+type manifest struct {
+	Version      int              `json:"version"`
+	BeetVersion  string           `json:"beet_version"`
+	Pack         string           `json:"pack"`
+	PackSHA256   string           `json:"pack_sha256"`
+	Options      manifestOptions  `json:"options"`
+	Intent       string           `json:"intent,omitempty"`
+	IntentSHA256 string           `json:"intent_sha256"`
+	Outputs      []manifestOutput `json:"outputs"`
+}
+
+// manifestOptions are the generate flags that change what gets rendered.
+type manifestOptions struct {
+	Template      string   `json:"template,omitempty"`
+	Vars          string   `json:"vars,omitempty"`
+	Set           []string `json:"set,omitempty"`
+	Context       bool     `json:"context,omitempty"`
+	ContextBudget int      `json:"context_budget,omitempty"`
+	Refine        bool     `json:"refine,omitempty"`
+	CLI           string   `json:"cli,omitempty"`
+}
+
+type manifestOutput struct {
+	File   string         `json:"file"`
+	SHA256 string         `json:"sha256"`
+	Inputs manifestInputs `json:"inputs"`
+}
+
+// manifestInput is one template, partial or guideline, or the resolved
+// placeholder values, that went into an output.
+type manifestInput struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name,omitempty"`
+	SHA256 string `json:"sha256"`
+}
+
+type manifestInputs []manifestInput
+
+func (in *manifestInputs) add(kind, name, text string) {
+	for _, existing := range *in {
+		if existing.Kind == kind && existing.Name == name {
+			return
+		}
+	}
+	*in = append(*in, manifestInput{Kind: kind, Name: name, SHA256: sha256Hex(text)})
+}
+
+// recordPartials wraps load so every partial a template includes is recorded.
+func (in *manifestInputs) recordPartials(load partialLoader) partialLoader {
+	return func(name string) (string, error) {
+		text, err := load(name)
+		if err == nil {
+			in.add("partial", normalizeTemplateName(name), text)
+		}
+		return text, err
+	}
+}
+
+func (i manifestInput) label() string {
+	return strings.TrimSpace(i.Kind + " " + i.Name)
+}
+
+// changedInputs lists the inputs whose hash differs between two runs,
+// including inputs that were added or dropped.
+func changedInputs(before, after manifestInputs) []string {
+	old := make(map[string]string, len(before))
+	for _, in := range before {
+		old[in.label()] = in.SHA256
+	}
+	var changed []string
+	for _, in := range after {
+		if sum, ok := old[in.label()]; !ok || sum != in.SHA256 {
+			changed = append(changed, in.label())
+		}
+		delete(old, in.label())
+	}
+	removed := make([]string, 0, len(old))
+	for label := range old {
+		removed = append(removed, label)
+	}
+	sort.Strings(removed)
+	return append(changed, removed...)
+}
+
+// canonical renders the values in a stable order for hashing, leaving out
+// the omitted keys.
+func (v placeholderValues) canonical(omit ...string) string {
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		if !slices.Contains(omit, k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + v[k] + "\x00")
+	}
+	return b.String()
+}
+
+func newManifest(r rendering, opts *generateOptions) (manifest, error) {
+	packYAML, err := yaml.Marshal(r.pack)
+	if err != nil {
+		return manifest{}, fmt.Errorf("encode pack %s: %w", r.packName, err)
+	}
+	m := manifest{
+		Version:     manifestVersion,
+		BeetVersion: beetVersion(),
+		Pack:        strings.TrimSuffix(normalizePackName(r.packName), ".yaml"),
+		PackSHA256:  sha256Hex(string(packYAML)),
+		Options: manifestOptions{
+			Template: opts.template,
+			Vars:     opts.varsFile,
+			Set:      opts.set,
+			Context:  opts.withContext,
+			Refine:   opts.refine,
+			CLI:      opts.cli,
+		},
+		IntentSHA256: sha256Hex(r.intent),
+	}
+	if opts.intentRecordable() {
+		m.Intent = r.intent
+	}
+	if opts.withContext {
+		m.Options.ContextBudget = opts.contextBudget
+	}
+	return m, nil
+}
+
+func beetVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+func manifestPath() string {
+	return filepath.Join(defaultConfigFolder, manifestFilename)
+}
+
+func writeManifest(m manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	// The manifest is meant to be committed, so it gets the usual file mode
+	// rather than writeFileAtomic's private one.
+	if err := os.MkdirAll(filepath.Dir(manifestPath()), 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(manifestPath()), err)
+	}
+	if err := os.WriteFile(manifestPath(), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	logVerbose("recorded %d outputs in %s", len(m.Outputs), manifestPath())
+	return nil
+}
+
+func loadManifest() (manifest, error) {
+	data, err := os.ReadFile(manifestPath())
+	if err != nil {
+		return manifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return manifest{}, fmt.Errorf("parse %s: %w", manifestPath(), err)
+	}
+	if m.Version > manifestVersion {
+		return manifest{}, fmt.Errorf("%s has version %d; upgrade beet to read it", manifestPath(), m.Version)
+	}
+	return m, nil
+}
+
+// errNoRecordedIntent explains why a manifest without an intent can't be
+// replayed; hint says how to pass the intent instead.
+func errNoRecordedIntent(hint string) error {
+	return fmt.Errorf("%s records no intent because it came from a ticket, --from-diff, stdin or the editor; %s", manifestPath(), hint)
+}
+
+func (m manifest) output(file string) (manifestOutput, bool) {
+	for _, out := range m.Outputs {
+		if out.File == file {
+			return out, true
+		}
+	}
+	return manifestOutput{}, false
+}
+
+// generateOptions replays the recorded pack, options and intent.
+func (m manifest) generateOptions() *generateOptions {
+	opts := &generateOptions{
+		pack:          m.Pack,
+		template:      m.Options.Template,
+		varsFile:      m.Options.Vars,
+		set:           m.Options.Set,
+		withContext:   m.Options.Context,
+		contextBudget: m.Options.ContextBudget,
+		refine:        m.Options.Refine,
+		cli:           m.Options.CLI,
+		intentSources: []intentSource{{value: m.Intent}},
+		diffBudget:    defaultDiffBudget,
+	}
+	if opts.contextBudget == 0 {
+		opts.contextBudget = defaultContextBudget
+	}
+	return opts
+}
+
+// handleRegenerate renders the outputs again from the intent and options in
+// the manifest, picking up any template, guideline or pack changes since.
+func handleRegenerate(configDir string, args []string) error {
+	fs := flag.NewFlagSet("regenerate", flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	dryRun := fs.Bool("dry-run", false, "render without writing files")
+	force := fs.Bool("force", false, "overwrite existing outputs regardless of their on_exists policy")
+	noClobber := fs.Bool("no-clobber", false, "never modify existing outputs")
+	noCache := fs.Bool("no-cache", false, "always call the CLI instead of reusing cached refinements")
+	fs.Usage = func() {
+		usagePrintln(fs.Output(), "Usage: beet regenerate [--dry-run] [--force|--no-clobber] [--no-cache]")
+		usagePrintln(fs.Output(), "\nReplays the pack, options and intent recorded in "+manifestPath()+".")
+		usagePrintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if len(fs.Args()) > 0 {
+		return fmt.Errorf("regenerate takes no intent; it replays the one in %s", manifestPath())
+	}
+
+	m, err := loadManifest()
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no %s here; run beet once to record one", manifestPath())
+	}
+	if err != nil {
+		return err
+	}
+	if m.Intent == "" {
+		return errNoRecordedIntent("run beet with the intent again instead")
+	}
+
+	opts := m.generateOptions()
+	opts.dryRun, opts.force, opts.noClobber, opts.noCache = *dryRun, *force, *noClobber, *noCache
+	writeOpts, err := opts.writeOptions()
+	if err != nil {
+		return err
+	}
+	return generate(configDir, opts, writeOpts)
+}
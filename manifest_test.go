@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func setupManifestPack(t *testing.T) string {
+	t.Helper()
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/rules.md", "{{> header}}\n{{intent}} for {{team}}\n{{guidelines}}\n")
+	writeConfigFile(t, configDir, "partials/header.md", "# Rules")
+	writeConfigFile(t, configDir, "guidelines/style.md", "Keep it short.\n")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: RULES.md\n    template: rules.md\n")
+	t.Chdir(t.TempDir())
+	return configDir
+}
+
+func TestGenerateWritesManifest(t *testing.T) {
+	configDir := setupManifestPack(t)
+
+	if err := handleGenerate(configDir, []string{"-p", "p", "--dry-run", "Ship it"}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if _, err := os.Stat(manifestPath()); !os.IsNotExist(err) {
+		t.Fatalf("dry run wrote a manifest: %v", err)
+	}
+
+	if err := handleGenerate(configDir, []string{"-p", "p", "--set", "team=core", "Ship it"}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	m, err := loadManifest()
+	if err != nil {
+		t.Fatalf("loadManifest: %v", err)
+	}
+	if m.Version != manifestVersion || m.Pack != "p" || m.Intent != "Ship it" || m.IntentSHA256 != sha256Hex("Ship it") {
+		t.Fatalf("manifest header: %+v", m)
+	}
+	if !reflect.DeepEqual(m.Options.Set, []string{"team=core"}) {
+		t.Fatalf("options: %+v", m.Options)
+	}
+
+	out, ok := m.output("RULES.md")
+	if !ok {
+		t.Fatalf("RULES.md missing from %+v", m.Outputs)
+	}
+	written, err := os.ReadFile("RULES.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.SHA256 != sha256Hex(string(written)) {
+		t.Fatal("output hash does not match the written file")
+	}
+	var labels []string
+	for _, in := range out.Inputs {
+		labels = append(labels, in.label())
+	}
+	want := []string{"template rules.md", "guideline style", "partial header.md", "placeholders"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Fatalf("inputs = %v, want %v", labels, want)
+	}
+}
+
+func TestRegenerateReplaysManifest(t *testing.T) {
+	configDir := setupManifestPack(t)
+	if err := os.WriteFile("INTENT.md", []byte("Ship it\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := handleGenerate(configDir, []string{"-p", "p", "--set", "team=core", "--intent-file", "INTENT.md"}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if err := os.Remove("INTENT.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfigFile(t, configDir, "guidelines/style.md", "Keep it shorter.\n")
+	if err := handleCheck(configDir, nil); err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Fatalf("check after guideline edit: %v", err)
+	}
+
+	if err := handleRegenerate(configDir, nil); err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+	b, err := os.ReadFile("RULES.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); !strings.Contains(got, "Ship it for core") || !strings.Contains(got, "Keep it shorter.") {
+		t.Fatalf("regenerated RULES.md:\n%s", got)
+	}
+	if err := handleCheck(configDir, nil); err != nil {
+		t.Fatalf("check after regenerate: %v", err)
+	}
+
+	if err := handleRegenerate(configDir, []string{"new intent"}); err == nil {
+		t.Fatal("expected regenerate to reject an intent")
+	}
+}
+
+func TestManifestRecordsOnlyCommandLineIntent(t *testing.T) {
+	configDir := setupManifestPack(t)
+	if err := handleGenerate(configDir, []string{"-p", "p", "--set", "team=core", "Ship it"}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	info, err := os.Stat(manifestPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o644 {
+		t.Fatalf("manifest mode = %v, want 0644", perm)
+	}
+
+	r := rendering{packName: "p", intent: "from stdin"}
+	m, err := newManifest(r, &generateOptions{intentSources: []intentSource{{value: "-"}}})
+	if err != nil {
+		t.Fatalf("newManifest: %v", err)
+	}
+	if m.Intent != "" || m.IntentSHA256 != sha256Hex("from stdin") {
+		t.Fatalf("stdin intent recorded: %+v", m)
+	}
+	if err := writeManifest(m); err != nil {
+		t.Fatalf("writeManifest: %v", err)
+	}
+	if err := handleRegenerate(configDir, nil); err == nil || !strings.Contains(err.Error(), "records no intent") {
+		t.Fatalf("regenerate: got %v", err)
+	}
+	if err := handleCheck(configDir, nil); err == nil || !strings.Contains(err.Error(), "--intent-file") {
+		t.Fatalf("check: got %v", err)
+	}
+}
+
+func TestRegenerateWithoutManifest(t *testing.T) {
+	configDir := setupManifestPack(t)
+	if err := handleRegenerate(configDir, nil); err == nil || !strings.Contains(err.Error(), "run beet once") {
+		t.Fatalf("got %v, want a missing manifest error", err)
+	}
+}
+
+func TestChangedInputs(t *testing.T) {
+	before := manifestInputs{
+		{Kind: "template", Name: "rules.md", SHA256: "1"},
+		{Kind: "guideline", Name: "style", SHA256: "2"},
+		{Kind: "partial", Name: "old.md", SHA256: "3"},
+		{Kind: "placeholders", SHA256: "4"},
+	}
+	after := manifestInputs{
+		{Kind: "template", Name: "rules.md", SHA256: "1"},
+		{Kind: "guideline", Name: "style", SHA256: "changed"},
+		{Kind: "guideline", Name: "testing", SHA256: "5"},
+		{Kind: "placeholders", SHA256: "4"},
+	}
+	want := []string{"guideline style", "guideline testing", "partial old.md"}
+	if got := changedInputs(before, after); !reflect.DeepEqual(got, want) {
+		t.Fatalf("changedInputs = %v, want %v", got, want)
+	}
+}
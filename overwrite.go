@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// onExistsPolicy says what writing an output does when the file is already
+// there. Unset merges managed regions when both sides have them and
+// overwrites otherwise, except agents.md, which is kept as it always was.
+type onExistsPolicy string
+
+const (
+	onExistsDefault   onExistsPolicy = ""
+	onExistsOverwrite onExistsPolicy = "overwrite"
+	onExistsSkip      onExistsPolicy = "skip"
+	onExistsBackup    onExistsPolicy = "backup"
+	onExistsMerge     onExistsPolicy = "merge"
+	onExistsFail      onExistsPolicy = "fail"
+)
+
+const backupSuffix = ".bak"
+
+var onExistsPolicies = []onExistsPolicy{onExistsOverwrite, onExistsSkip, onExistsBackup, onExistsMerge, onExistsFail}
+
+func (p onExistsPolicy) valid() bool {
+	if p == onExistsDefault {
+		return true
+	}
+	for _, known := range onExistsPolicies {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// writeOptions are the command-line overrides of every output's policy.
+// forceAgents is the deprecated --force-agents, which only ever applied to
+// agents.md.
+type writeOptions struct {
+	force       bool
+	forceAgents bool
+	noClobber   bool
+}
+
+func (o writeOptions) policyFor(out packOutput) onExistsPolicy {
+	switch {
+	case o.force, o.forceAgents && isAgentsFile(out.File):
+		return onExistsOverwrite
+	case o.noClobber:
+		return onExistsSkip
+	}
+	return out.OnExists
+}
+
+// outputPlan is what writing one output would do to the file on disk.
+// conflict marks an existing on_exists: fail output that would change; the
+// plan still carries the overwrite so diff and check can show it, but apply
+// refuses to write it.
+type outputPlan struct {
+	path     string
+	exists   bool
+	old      string
+	content  string
+	write    bool
+	backup   bool
+	conflict bool
+}
+
+func (p outputPlan) changed() bool {
+	return p.write && (!p.exists || p.old != p.content)
+}
+
+// planOutput applies policy to content and the current file at path without
+// writing anything.
+func planOutput(path string, content string, policy onExistsPolicy) (outputPlan, error) {
+	plan := outputPlan{path: path, content: content, write: true}
+	existing, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return plan, nil
+	}
+	if err != nil {
+		return outputPlan{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	plan.exists, plan.old = true, string(existing)
+
+	switch policy {
+	case onExistsSkip:
+		logVerbose("skipping %s: it exists (on_exists: skip)", path)
+		plan.write, plan.content = false, plan.old
+	case onExistsFail:
+		plan.conflict = plan.old != content
+	case onExistsBackup:
+		plan.backup = true
+	case onExistsMerge, onExistsDefault:
+		merged, ok, err := mergeExistingOutput(path, plan.old, content)
+		if err != nil {
+			return outputPlan{}, err
+		}
+		if ok {
+			plan.content = merged
+		} else if policy == onExistsMerge {
+			logWarning("leaving %s unchanged (on_exists: merge): the file or the render has no managed regions to merge, which is usual for --refine output", path)
+			plan.write, plan.content = false, plan.old
+		} else if isAgentsFile(path) {
+			logWarning("leaving %s unchanged as before; this default is deprecated, so set on_exists for it in your pack (merge keeps it, overwrite replaces it)", path)
+			plan.write, plan.content = false, plan.old
+		}
+	}
+	if plan.content == plan.old {
+		// Nothing to write, and a backup would replace the previous version
+		// with this one.
+		plan.write, plan.backup = false, false
+	}
+	return plan, nil
+}
+
+// isAgentsFile reports whether path is an agents.md, which beet never
+// overwrote before on_exists existed.
+func isAgentsFile(path string) bool {
+	return strings.EqualFold(filepath.Base(path), agentsFilename)
+}
+
+func (p outputPlan) conflictError() error {
+	return fmt.Errorf("%s already exists (on_exists: fail); use --force to overwrite it", p.path)
+}
+
+func (p outputPlan) apply() error {
+	if !p.write {
+		return nil
+	}
+	if p.conflict {
+		return p.conflictError()
+	}
+	if p.backup {
+		if err := os.WriteFile(p.path+backupSuffix, []byte(p.old), 0o644); err != nil {
+			return fmt.Errorf("back up %s: %w", p.path, err)
+		}
+		logVerbose("backed up %s to %s", p.path, p.path+backupSuffix)
+	}
+
+	if dir := filepath.Dir(p.path); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create output dir: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(p.path, []byte(p.content), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", p.path, err)
+	}
+	return nil
+}
+
+// writeRenderedOutput applies policy and writes the result, returning the plan
+// so callers know what ended up on disk.
+func writeRenderedOutput(path string, content string, policy onExistsPolicy) (outputPlan, error) {
+	plan, err := planOutput(path, content, policy)
+	if err != nil {
+		return outputPlan{}, err
+	}
+	return plan, plan.apply()
+}
+
+func onExistsUsage() string {
+	names := make([]string, len(onExistsPolicies))
+	for i, p := range onExistsPolicies {
+		names[i] = string(p)
+	}
+	return strings.Join(names, ", ")
+}
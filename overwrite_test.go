@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteRenderedOutputPolicies(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "OUT.md")
+	write := func(policy onExistsPolicy) error {
+		if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+		_, err := writeRenderedOutput(path, "new\n", policy)
+		return err
+	}
+	read := func(p string) string {
+		b, _ := os.ReadFile(p)
+		return string(b)
+	}
+
+	for _, policy := range []onExistsPolicy{onExistsDefault, onExistsOverwrite} {
+		if err := write(policy); err != nil || read(path) != "new\n" {
+			t.Fatalf("%q: got %q, %v", policy, read(path), err)
+		}
+	}
+	if err := write(onExistsSkip); err != nil || read(path) != "old\n" {
+		t.Fatalf("skip: got %q, %v", read(path), err)
+	}
+	if err := write(onExistsMerge); err != nil || read(path) != "old\n" {
+		t.Fatalf("merge without regions: got %q, %v", read(path), err)
+	}
+	if err := write(onExistsFail); err == nil || !strings.Contains(err.Error(), "already exists (on_exists: fail)") || read(path) != "old\n" {
+		t.Fatalf("fail: got %q, %v", read(path), err)
+	}
+	if err := write(onExistsBackup); err != nil || read(path) != "new\n" || read(path+backupSuffix) != "old\n" {
+		t.Fatalf("backup: got %q / %q, %v", read(path), read(path+backupSuffix), err)
+	}
+	if _, err := writeRenderedOutput(path, "new\n", onExistsBackup); err != nil || read(path+backupSuffix) != "old\n" {
+		t.Fatalf("unchanged backup rerun: got %q, %v", read(path+backupSuffix), err)
+	}
+
+	fresh := filepath.Join(dir, "sub", "FRESH.md")
+	if _, err := writeRenderedOutput(fresh, "new\n", onExistsFail); err != nil || read(fresh) != "new\n" {
+		t.Fatalf("new file with fail policy: got %q, %v", read(fresh), err)
+	}
+}
+
+func TestWriteRenderedOutputKeepsUnmanagedAgentsFile(t *testing.T) {
+	warnings := captureWarnings(t)
+	agents := filepath.Join(t.TempDir(), agentsFilename)
+	if err := os.WriteFile(agents, []byte("hand written\n"), 0o644); err != nil {
+		t.Fatalf("write agents: %v", err)
+	}
+
+	plan, err := writeRenderedOutput(agents, "## Agents\nrules\n", onExistsDefault)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got, _ := os.ReadFile(agents); string(got) != "hand written\n" || plan.changed() {
+		t.Fatalf("unset policy overwrote agents.md: %q", got)
+	}
+	if !strings.Contains(warnings.String(), "set on_exists for it in your pack") {
+		t.Fatalf("warnings = %q", warnings.String())
+	}
+}
+
+func TestWriteOptionsPolicyFor(t *testing.T) {
+	out := packOutput{File: "PROGRESS.md", OnExists: onExistsSkip}
+	if got := (writeOptions{}).policyFor(out); got != onExistsSkip {
+		t.Fatalf("pack policy = %q", got)
+	}
+	if got := (writeOptions{force: true}).policyFor(out); got != onExistsOverwrite {
+		t.Fatalf("--force policy = %q", got)
+	}
+	if got := (writeOptions{noClobber: true}).policyFor(packOutput{}); got != onExistsSkip {
+		t.Fatalf("--no-clobber policy = %q", got)
+	}
+	agents := packOutput{File: "docs/AGENTS.md", OnExists: onExistsMerge}
+	if got := (writeOptions{forceAgents: true}).policyFor(agents); got != onExistsOverwrite {
+		t.Fatalf("--force-agents policy for agents.md = %q", got)
+	}
+	if got := (writeOptions{forceAgents: true}).policyFor(out); got != onExistsSkip {
+		t.Fatalf("--force-agents policy for %s = %q", out.File, got)
+	}
+	if _, err := (&generateOptions{forceAgents: true, noClobber: true}).writeOptions(); err == nil {
+		t.Fatal("--force-agents with --no-clobber: expected an error")
+	}
+}
+
+func TestLoadPackOnExists(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(dir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, dir, "packs/base.yaml", "outputs:\n  - file: PLAN.md\n    template: plan.md\n  - file: PROGRESS.md\n    template: progress.md\n    on_exists: skip\n")
+	writeConfigFile(t, dir, "packs/child.yaml", "extends: base\noutputs:\n  - file: PLAN.md\n    on_exists: backup\n")
+	writeConfigFile(t, dir, "packs/bad.yaml", "outputs:\n  - file: X.md\n    template: x.md\n    on_exists: clobber\n")
+
+	p, err := loadPack(dir, "child")
+	if err != nil {
+		t.Fatalf("loadPack: %v", err)
+	}
+	if p.Outputs[0].OnExists != onExistsBackup || p.Outputs[1].OnExists != onExistsSkip {
+		t.Fatalf("on_exists = %q, %q", p.Outputs[0].OnExists, p.Outputs[1].OnExists)
+	}
+
+	if _, err := loadPack(dir, "bad"); err == nil || !strings.Contains(err.Error(), `unknown on_exists "clobber"`) {
+		t.Fatalf("expected on_exists error, got %v", err)
+	}
+}
+
+func TestHandleGenerateOverwriteFlags(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "{{intent}}\n")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: ONCE.md\n    template: t.md\n    on_exists: skip\n  - file: ALWAYS.md\n    template: t.md\n")
+	t.Chdir(t.TempDir())
+
+	run := func(args ...string) {
+		t.Helper()
+		if err := handleGenerate(configDir, append([]string{"-p", "p"}, args...)); err != nil {
+			t.Fatalf("handleGenerate %v: %v", args, err)
+		}
+	}
+	lastLine := func(name string) string {
+		b, _ := os.ReadFile(name)
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		return lines[len(lines)-1]
+	}
+	contents := func() (string, string) {
+		return lastLine("ONCE.md"), lastLine("ALWAYS.md")
+	}
+
+	run("first")
+	run("second")
+	if once, always := contents(); once != "first" || always != "second" {
+		t.Fatalf("after regenerate: ONCE=%q ALWAYS=%q", once, always)
+	}
+	run("--no-clobber", "third")
+	if once, always := contents(); once != "first" || always != "second" {
+		t.Fatalf("after --no-clobber: ONCE=%q ALWAYS=%q", once, always)
+	}
+	run("--force", "fourth")
+	if once, always := contents(); once != "fourth" || always != "fourth" {
+		t.Fatalf("after --force: ONCE=%q ALWAYS=%q", once, always)
+	}
+
+	if err := handleGenerate(configDir, []string{"-p", "p", "--force", "--no-clobber", "x"}); err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+		t.Fatalf("expected flag conflict error, got %v", err)
+	}
+}
+
+func TestHandleGenerateFailConflictWritesNothing(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "{{intent}}\n")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: A.md\n    template: t.md\n  - file: B.md\n    template: t.md\n    on_exists: fail\n")
+	t.Chdir(t.TempDir())
+
+	if err := handleGenerate(configDir, []string{"-p", "p", "v1"}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	before, err := os.ReadFile(manifestPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handleGenerate(configDir, []string{"-p", "p", "v2"}); err == nil || !strings.Contains(err.Error(), "B.md already exists") {
+		t.Fatalf("got %v, want a conflict on B.md", err)
+	}
+	if a, _ := os.ReadFile("A.md"); strings.Contains(string(a), "v2") {
+		t.Fatalf("A.md was written before the conflict:\n%s", a)
+	}
+	if after, _ := os.ReadFile(manifestPath()); string(after) != string(before) {
+		t.Fatal("manifest changed after the conflict")
+	}
+}
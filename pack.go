@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+import "gopkg.in/yaml.v3"
+
+type pack struct {
+	Extends packParents       `yaml:"extends,omitempty"`
+	Remove  []string          `yaml:"remove,omitempty"`
+	Vars    placeholderValues `yaml:"vars,omitempty"`
+	Outputs []packOutput      `yaml:"outputs"`
+}
+
+type packOutput struct {
+	File       string             `yaml:"file"`
+	Template   string             `yaml:"template"`
+	Vars       placeholderValues  `yaml:"vars,omitempty"`
+	Guidelines *guidelineSelector `yaml:"guidelines,omitempty"`
+	OnExists   onExistsPolicy     `yaml:"on_exists,omitempty"`
+}
+
+// packParents accepts either `extends: default` or `extends: [a, b]`.
+type packParents []string
+
+func (p *packParents) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*p = packParents{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		var names []string
+		if err := node.Decode(&names); err != nil {
+			return err
+		}
+		*p = names
+		return nil
+	}
+	return fmt.Errorf("line %d: extends must be a pack name or a list of pack names", node.Line)
+}
+
+func normalizePackName(name string) string {
+	if name == "" {
+		name = defaultPackName
+	}
+	if !strings.HasSuffix(name, ".yaml") {
+		name += ".yaml"
+	}
+	return name
+}
+
+func loadPack(configDir, name string) (pack, error) {
+	name = normalizePackName(name)
+
+	p, err := resolvePack(configDir, name, nil)
+	if err != nil {
+		return pack{}, err
+	}
+
+	if len(p.Outputs) == 0 {
+		return pack{}, fmt.Errorf("pack %s has no outputs", name)
+	}
+
+	for i, out := range p.Outputs {
+		if strings.TrimSpace(out.File) == "" {
+			return pack{}, fmt.Errorf("pack %s output %d missing file", name, i)
+		}
+		if strings.TrimSpace(out.Template) == "" {
+			return pack{}, fmt.Errorf("pack %s output %d missing template", name, i)
+		}
+		if !out.OnExists.valid() {
+			return pack{}, fmt.Errorf("pack %s output %s: unknown on_exists %q (want %s)", name, out.File, out.OnExists, onExistsUsage())
+		}
+	}
+
+	return p, nil
+}
+
+func readPackFile(configDir, name string) (pack, error) {
+	data, err := os.ReadFile(packPath(configDir, name))
+	if err != nil {
+		return pack{}, fmt.Errorf("load pack %s: %w", name, err)
+	}
+
+	var p pack
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return pack{}, fmt.Errorf("parse pack %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// resolvePack flattens name and its parents into a single pack. Parents are
+// applied in order, then the pack's own removals and outputs; an output whose
+// file matches an inherited one replaces it in place. Vars merge the same way,
+// with the child winning.
+func resolvePack(configDir, name string, stack []string) (pack, error) {
+	for _, seen := range stack {
+		if seen == name {
+			return pack{}, fmt.Errorf("pack cycle: %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+	stack = append(stack, name)
+
+	p, err := readPackFile(configDir, name)
+	if err != nil {
+		return pack{}, err
+	}
+
+	var outputs []packOutput
+	vars := placeholderValues{}
+	for _, parent := range p.Extends {
+		if strings.TrimSpace(parent) == "" {
+			return pack{}, fmt.Errorf("pack %s extends an empty pack name", name)
+		}
+		base, err := resolvePack(configDir, normalizePackName(parent), stack)
+		if err != nil {
+			return pack{}, err
+		}
+		vars = vars.merge(base.Vars)
+		for _, out := range base.Outputs {
+			outputs = mergePackOutput(outputs, out)
+		}
+	}
+
+	for _, file := range p.Remove {
+		i := packOutputIndex(outputs, file)
+		if i < 0 {
+			return pack{}, fmt.Errorf("pack %s removes %s, which no parent defines", name, file)
+		}
+		outputs = append(outputs[:i], outputs[i+1:]...)
+	}
+
+	for _, out := range p.Outputs {
+		outputs = mergePackOutput(outputs, out)
+	}
+
+	return pack{Vars: vars.merge(p.Vars), Outputs: outputs}, nil
+}
+
+func mergePackOutput(outputs []packOutput, out packOutput) []packOutput {
+	i := packOutputIndex(outputs, out.File)
+	if i < 0 {
+		return append(outputs, out)
+	}
+	merged := outputs[i]
+	if out.Template != "" {
+		merged.Template = out.Template
+	}
+	if len(out.Vars) > 0 {
+		merged.Vars = merged.Vars.merge(out.Vars)
+	}
+	if out.Guidelines != nil {
+		merged.Guidelines = out.Guidelines
+	}
+	if out.OnExists != onExistsDefault {
+		merged.OnExists = out.OnExists
+	}
+	outputs[i] = merged
+	return outputs
+}
+
+func packOutputIndex(outputs []packOutput, file string) int {
+	file = strings.TrimSpace(file)
+	for i, out := range outputs {
+		if file != "" && strings.EqualFold(out.File, file) {
+			return i
+		}
+	}
+	return -1
+}
+
+func packPath(configDir, filename string) string {
+	if path, ok := findLayeredFile(configDir, packsDirName, filename); ok {
+		return path
+	}
+	return filepath.Join(configDir, packsDirName, filename)
+}
+
+func showPack(w io.Writer, configDir, name string, resolved bool) error {
+	name = normalizePackName(name)
+	if !resolved {
+		data, err := os.ReadFile(packPath(configDir, name))
+		if err != nil {
+			return fmt.Errorf("load pack %s: %w", name, err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	p, err := loadPack(configDir, name)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(p); err != nil {
+		return fmt.Errorf("encode pack %s: %w", name, err)
+	}
+	return enc.Close()
+}
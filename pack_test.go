@@ -0,0 +1,134 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func packFiles(p pack) []string {
+	var out []string
+	for _, o := range p.Outputs {
+		out = append(out, o.File+"="+o.Template)
+	}
+	return out
+}
+
+func TestLoadPackResolvesBundledExtends(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(dir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	if err := bootstrapDefaults(dir); err != nil {
+		t.Fatalf("bootstrapDefaults: %v", err)
+	}
+
+	p, err := loadPack(dir, "extended")
+	if err != nil {
+		t.Fatalf("loadPack extended: %v", err)
+	}
+	want := []string{"WORK_PROMPT.md=default.md", "agents.md=agents.md", "PRD.md=prd.md", "SRS.md=srs.md", "GUIDELINES.md=guidelines.md"}
+	if got := packFiles(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("extended outputs = %v, want %v", got, want)
+	}
+}
+
+func TestLoadPackOverridesAndRemovesInheritedOutputs(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "packs/base.yaml", "outputs:\n  - file: A.md\n    template: a.md\n  - file: B.md\n    template: b.md\n")
+	writeConfigFile(t, dir, "packs/extra.yaml", "outputs:\n  - file: C.md\n    template: c.md\n  - file: A.md\n    template: extra-a.md\n")
+	writeConfigFile(t, dir, "packs/child.yaml", "extends: [base, extra]\nremove: [b.md]\noutputs:\n  - file: C.md\n    template: child-c.md\n  - file: D.md\n    template: d.md\n")
+
+	p, err := loadPack(dir, "child")
+	if err != nil {
+		t.Fatalf("loadPack child: %v", err)
+	}
+	want := []string{"A.md=extra-a.md", "C.md=child-c.md", "D.md=d.md"}
+	if got := packFiles(p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("child outputs = %v, want %v", got, want)
+	}
+}
+
+func TestLoadPackInheritanceErrors(t *testing.T) {
+	cases := map[string]struct {
+		packs map[string]string
+		want  string
+	}{
+		"cycle": {
+			packs: map[string]string{"a.yaml": "extends: b\n", "b.yaml": "extends: a\n"},
+			want:  "pack cycle: a.yaml -> b.yaml -> a.yaml",
+		},
+		"unknown removal": {
+			packs: map[string]string{"a.yaml": "extends: b\nremove: [X.md]\n", "b.yaml": "outputs:\n  - file: Y.md\n    template: y.md\n"},
+			want:  "removes X.md",
+		},
+		"missing parent": {
+			packs: map[string]string{"a.yaml": "extends: nope\n"},
+			want:  "load pack nope.yaml",
+		},
+		"bad extends": {
+			packs: map[string]string{"a.yaml": "extends: {x: 1}\n"},
+			want:  "extends must be a pack name",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			for file, content := range tc.packs {
+				writeConfigFile(t, dir, "packs/"+file, content)
+			}
+			_, err := loadPack(dir, "a")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("loadPack error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestShowPackResolved(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "packs/base.yaml", "outputs:\n  - file: A.md\n    template: a.md\n")
+	writeConfigFile(t, dir, "packs/child.yaml", "extends: base\noutputs:\n  - file: B.md\n    template: b.md\n")
+
+	var raw strings.Builder
+	if err := showPack(&raw, dir, "child", false); err != nil {
+		t.Fatalf("showPack raw: %v", err)
+	}
+	if !strings.HasPrefix(raw.String(), "extends: base\n") {
+		t.Fatalf("raw pack = %q", raw.String())
+	}
+
+	var resolved strings.Builder
+	if err := showPack(&resolved, dir, "child", true); err != nil {
+		t.Fatalf("showPack resolved: %v", err)
+	}
+	want := "outputs:\n  - file: A.md\n    template: a.md\n  - file: B.md\n    template: b.md\n"
+	if resolved.String() != want {
+		t.Fatalf("resolved pack = %q, want %q", resolved.String(), want)
+	}
+}
+
+func TestLoadPackMergesVars(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "packs/base.yaml", "vars:\n  audience: engineers\n  Project Name: beet\noutputs:\n  - file: A.md\n    template: a.md\n    vars:\n      tone: terse\n      focus: api\n")
+	writeConfigFile(t, dir, "packs/child.yaml", "extends: base\nvars:\n  audience: executives\n  stakeholders: [product, legal]\noutputs:\n  - file: A.md\n    vars:\n      tone: friendly\n")
+
+	p, err := loadPack(dir, "child")
+	if err != nil {
+		t.Fatalf("loadPack child: %v", err)
+	}
+	wantPack := placeholderValues{"audience": "executives", "project_name": "beet", "stakeholders": "- product\n- legal"}
+	if !reflect.DeepEqual(p.Vars, wantPack) {
+		t.Fatalf("pack vars = %v, want %v", p.Vars, wantPack)
+	}
+	out := p.Outputs[0]
+	if out.Template != "a.md" || !reflect.DeepEqual(out.Vars, placeholderValues{"tone": "friendly", "focus": "api"}) {
+		t.Fatalf("output = %+v", out)
+	}
+
+	writeConfigFile(t, dir, "packs/bad.yaml", "vars:\n  \"no good!\": x\noutputs:\n  - file: A.md\n    template: a.md\n")
+	if _, err := loadPack(dir, "bad"); err == nil || !strings.Contains(err.Error(), "invalid placeholder name") {
+		t.Fatalf("expected invalid placeholder error, got %v", err)
+	}
+}
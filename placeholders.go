@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+import "gopkg.in/yaml.v3"
+
+type placeholderValues map[string]string
+
+func basePlaceholders(intent, guidelines string) placeholderValues {
+	return placeholderValues{
+		"intent":      strings.TrimSpace(intent),
+		"intent_full": strings.TrimSpace(intent),
+		"guidelines":  guidelines,
+	}
+}
+
+func (v placeholderValues) merge(other placeholderValues) placeholderValues {
+	out := make(placeholderValues, len(v)+len(other))
+	for k, val := range v {
+		out[k] = val
+	}
+	for k, val := range other {
+		out[k] = val
+	}
+	return out
+}
+
+func normalizePlaceholderName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '/':
+			return '_'
+		}
+		return r
+	}, name)
+	return name
+}
+
+func validPlaceholderName(name string) bool {
+	return name != "." && templateVarPattern.MatchString(name)
+}
+
+func loadVarsFile(path string) (placeholderValues, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read vars file: %w", err)
+	}
+
+	var values placeholderValues
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("parse vars file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// UnmarshalYAML reads a mapping of placeholder names to values, as used by
+// --vars files and pack vars. Lists become bullet lines.
+func (v *placeholderValues) UnmarshalYAML(node *yaml.Node) error {
+	var raw map[string]interface{}
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	out := make(placeholderValues, len(raw))
+	for key, value := range raw {
+		name := normalizePlaceholderName(key)
+		if !validPlaceholderName(name) {
+			return fmt.Errorf("invalid placeholder name %q", key)
+		}
+		out[name] = formatPlaceholderValue(value)
+	}
+	*v = out
+	return nil
+}
+
+func formatPlaceholderValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case []interface{}:
+		lines := make([]string, 0, len(v))
+		for _, item := range v {
+			if text := formatPlaceholderValue(item); text != "" {
+				lines = append(lines, "- "+text)
+			}
+		}
+		return strings.Join(lines, "\n")
+	default:
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+}
+
+func parseSetValues(assignments []string) (placeholderValues, error) {
+	out := make(placeholderValues, len(assignments))
+	for _, assignment := range assignments {
+		key, value, ok := strings.Cut(assignment, "=")
+		name := normalizePlaceholderName(key)
+		if !ok || !validPlaceholderName(name) {
+			return nil, fmt.Errorf("invalid --set %q; want key=value", assignment)
+		}
+		out[name] = value
+	}
+	return out, nil
+}
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
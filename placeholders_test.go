@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadVarsFileFormatsValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	content := "Goals: ship it\nacceptance criteria:\n  - tests pass\n  - docs updated\nbudget: 3\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write vars: %v", err)
+	}
+
+	got, err := loadVarsFile(path)
+	if err != nil {
+		t.Fatalf("loadVarsFile returned error: %v", err)
+	}
+
+	want := placeholderValues{
+		"goals":               "ship it",
+		"acceptance_criteria": "- tests pass\n- docs updated",
+		"budget":              "3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("loadVarsFile = %v, want %v", got, want)
+	}
+}
+
+func TestParseSetValues(t *testing.T) {
+	got, err := parseSetValues([]string{"risks=none", "open-questions=a=b"})
+	if err != nil {
+		t.Fatalf("parseSetValues returned error: %v", err)
+	}
+	want := placeholderValues{"risks": "none", "open_questions": "a=b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseSetValues = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"novalue", "=x", "bad key!=x"} {
+		if _, err := parseSetValues([]string{bad}); err == nil {
+			t.Fatalf("parseSetValues(%q) should error", bad)
+		} else if !strings.Contains(err.Error(), "key=value") {
+			t.Fatalf("unexpected error for %q: %v", bad, err)
+		}
+	}
+}
+
+func TestResolvePlaceholderInputsPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := os.WriteFile(path, []byte("goals: from file\nrisks: from file\n"), 0o644); err != nil {
+		t.Fatalf("write vars: %v", err)
+	}
+
+	inputs, err := resolvePlaceholderInputs(" ship ", path, []string{"risks=from flag"})
+	if err != nil {
+		t.Fatalf("resolvePlaceholderInputs returned error: %v", err)
+	}
+	got := inputs.forOutput(pack{}, packOutput{}, "rules")
+
+	want := placeholderValues{
+		"intent":      "ship",
+		"intent_full": "ship",
+		"guidelines":  "rules",
+		"goals":       "from file",
+		"risks":       "from flag",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("placeholder values = %v, want %v", got, want)
+	}
+}
+
+func TestPlaceholderInputsLayerPackVars(t *testing.T) {
+	inputs, err := resolvePlaceholderInputs("ship\n\n## Goals\nfrom intent", "", []string{"tone=from flag"})
+	if err != nil {
+		t.Fatalf("resolvePlaceholderInputs returned error: %v", err)
+	}
+
+	p := pack{Vars: placeholderValues{"audience": "pack", "tone": "pack", "goals": "pack"}}
+	out := packOutput{Vars: placeholderValues{"audience": "output"}}
+	got := inputs.forOutput(p, out, "rules")
+
+	for name, want := range map[string]string{"audience": "output", "tone": "from flag", "goals": "from intent"} {
+		if got[name] != want {
+			t.Fatalf("%s = %q, want %q", name, got[name], want)
+		}
+	}
+}
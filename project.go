@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+import "gopkg.in/yaml.v3"
+
+// findProjectConfigDir walks up from the working directory looking for a
+// .beet directory that is not the user-level config dir itself.
+func findProjectConfigDir(userDir string) (string, bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+
+	skip := map[string]bool{sameFileKey(userDir): true}
+	home, err := os.UserHomeDir()
+	if err == nil {
+		skip[sameFileKey(filepath.Join(home, defaultConfigFolder))] = true
+		home = sameFileKey(home)
+	}
+
+	dir := sameFileKey(cwd)
+	for {
+		candidate := filepath.Join(dir, defaultConfigFolder)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() && !skip[sameFileKey(candidate)] && !manifestOnly(candidate) {
+			return candidate, true
+		}
+		if dir == home {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// manifestOnly reports whether dir holds nothing but a generation manifest,
+// which generating in a subdirectory creates and which is not project config.
+func manifestOnly(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	return err == nil && len(entries) == 1 && entries[0].Name() == manifestFilename
+}
+
+func sameFileKey(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// configLayers returns the directories consulted for templates, guidelines,
+// packs and partials, highest precedence first.
+func configLayers(configDir string) []string {
+	if project, ok := findProjectConfigDir(configDir); ok {
+		logVerbose("layering project config %s over %s", project, configDir)
+		return []string{project, configDir}
+	}
+	return []string{configDir}
+}
+
+func findLayeredFile(configDir, sub, name string) (string, bool) {
+	for _, layer := range configLayers(configDir) {
+		path := filepath.Join(layer, sub, filepath.FromSlash(name))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// layeredEntries maps each file name in sub to its path in the highest
+// precedence layer. Only the user-level directory is required to exist.
+func layeredEntries(configDir, sub string) (map[string]string, error) {
+	layers := configLayers(configDir)
+	out := make(map[string]string)
+	for i := len(layers) - 1; i >= 0; i-- {
+		dir := filepath.Join(layers[i], sub)
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) && i < len(layers)-1 {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", sub, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			out[entry.Name()] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return out, nil
+}
+
+func sortedEntryNames(entries map[string]string) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+const projectSettingsFilename = "config.yaml"
+
+type projectSettings struct {
+	Pack string `yaml:"pack,omitempty"`
+}
+
+func loadProjectSettings(configDir string) (projectSettings, string, error) {
+	project, ok := findProjectConfigDir(configDir)
+	if !ok {
+		return projectSettings{}, "", nil
+	}
+
+	path := filepath.Join(project, projectSettingsFilename)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return projectSettings{}, "", nil
+	}
+	if err != nil {
+		return projectSettings{}, "", fmt.Errorf("read project settings: %w", err)
+	}
+
+	var settings projectSettings
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return projectSettings{}, "", fmt.Errorf("parse %s: %w", path, err)
+	}
+	return settings, path, nil
+}
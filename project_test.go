@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", rel, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", rel, err)
+	}
+}
+
+func setupProjectOverlay(t *testing.T) (userDir, projectDir string) {
+	t.Helper()
+	userDir = filepath.Join(t.TempDir(), "user")
+	if err := ensureConfigStructure(userDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	writeConfigFile(t, userDir, "templates/default.md", "user default")
+	writeConfigFile(t, userDir, "templates/user-only.md", "user only")
+	writeConfigFile(t, userDir, "packs/default.yaml", "outputs:\n  - file: WORK_PROMPT.md\n    template: default.md\n")
+	writeConfigFile(t, userDir, "guidelines/a.md", "user a")
+	writeConfigFile(t, userDir, "guidelines/b.md", "user b")
+
+	repo := t.TempDir()
+	projectDir = filepath.Join(repo, defaultConfigFolder)
+	writeConfigFile(t, projectDir, "templates/default.md", "project default")
+	writeConfigFile(t, projectDir, "packs/team.yaml", "outputs:\n  - file: TEAM.md\n    template: default.md\n")
+	writeConfigFile(t, projectDir, "guidelines/b.md", "project b")
+
+	nested := filepath.Join(repo, "src", "pkg")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("mkdir nested: %v", err)
+	}
+	t.Chdir(nested)
+	return userDir, projectDir
+}
+
+func TestFindProjectConfigDirWalksUp(t *testing.T) {
+	userDir, projectDir := setupProjectOverlay(t)
+
+	got, ok := findProjectConfigDir(userDir)
+	if !ok || sameFileKey(got) != sameFileKey(projectDir) {
+		t.Fatalf("findProjectConfigDir = %q, %t; want %s", got, ok, projectDir)
+	}
+
+	if _, ok := findProjectConfigDir(projectDir); ok {
+		t.Fatalf("user config dir should not be reported as a project overlay")
+	}
+}
+
+func TestFindProjectConfigDirSkipsManifestOnlyDirs(t *testing.T) {
+	userDir, projectDir := setupProjectOverlay(t)
+	writeConfigFile(t, defaultConfigFolder, manifestFilename, "{}\n")
+
+	got, ok := findProjectConfigDir(userDir)
+	if !ok || sameFileKey(got) != sameFileKey(projectDir) {
+		t.Fatalf("findProjectConfigDir = %q, %t; want %s", got, ok, projectDir)
+	}
+}
+
+func TestProjectOverlayWinsOnNameClash(t *testing.T) {
+	userDir, _ := setupProjectOverlay(t)
+
+	tmpl, err := loadTemplate(userDir, "default")
+	if err != nil || tmpl != "project default" {
+		t.Fatalf("loadTemplate default = %q, %v", tmpl, err)
+	}
+	tmpl, err = loadTemplate(userDir, "user-only")
+	if err != nil || tmpl != "user only" {
+		t.Fatalf("loadTemplate user-only = %q, %v", tmpl, err)
+	}
+
+	templates, err := listTemplates(userDir)
+	if err != nil || !reflect.DeepEqual(templates, []string{"default.md", "user-only.md"}) {
+		t.Fatalf("listTemplates = %v, %v", templates, err)
+	}
+	packs, err := listPacks(userDir)
+	if err != nil || !reflect.DeepEqual(packs, []string{"default.yaml", "team.yaml"}) {
+		t.Fatalf("listPacks = %v, %v", packs, err)
+	}
+	if _, err := loadPack(userDir, "team"); err != nil {
+		t.Fatalf("loadPack team: %v", err)
+	}
+
+	guidelines, err := loadGuidelines(userDir)
+	if err != nil {
+		t.Fatalf("loadGuidelines: %v", err)
+	}
+	var got []string
+	for _, g := range guidelines {
+		got = append(got, g.name+"="+g.content)
+	}
+	if !reflect.DeepEqual(got, []string{"a=user a", "b=project b"}) {
+		t.Fatalf("guidelines = %v", got)
+	}
+}
+
+func TestRunDoctorReportsProjectConfig(t *testing.T) {
+	userDir, projectDir := setupProjectOverlay(t)
+	t.Setenv("PATH", "")
+	t.Setenv(envCLIBinary, "")
+
+	var b strings.Builder
+	if err := runDoctor(&b, userDir); err != nil {
+		t.Fatalf("runDoctor: %v", err)
+	}
+	if !strings.Contains(b.String(), "project config: ") || !strings.Contains(b.String(), filepath.Base(filepath.Dir(projectDir))) {
+		t.Fatalf("doctor output missing project config: %s", b.String())
+	}
+}
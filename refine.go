@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	envCLITimeout     = "BEET_CLI_TIMEOUT"
+	defaultCLITimeout = 5 * time.Minute
+)
+
+type llmBackend interface {
+	name() string
+	complete(ctx context.Context, prompt string) (string, error)
+}
+
+type cliBackend struct {
+	cli     detectedCLI
+	adapter cliAdapter
+}
+
+func (b cliBackend) name() string {
+	return b.cli.name
+}
+
+func (b cliBackend) complete(ctx context.Context, prompt string) (string, error) {
+	promptFile := ""
+	if b.adapter.Prompt == promptViaFile || argsReference(b.adapter.Args, promptFileArgPlaceholder) {
+		f, err := os.CreateTemp("", "beet-prompt-*.md")
+		if err != nil {
+			return "", fmt.Errorf("create prompt file: %w", err)
+		}
+		promptFile = f.Name()
+		defer func() {
+			_ = os.Remove(promptFile)
+		}()
+		if _, err := f.WriteString(prompt); err != nil {
+			_ = f.Close()
+			return "", fmt.Errorf("write prompt file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return "", fmt.Errorf("close prompt file: %w", err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.cli.path, b.adapter.command(prompt, promptFile)...)
+	if b.adapter.Prompt == promptViaStdin {
+		cmd.Stdin = strings.NewReader(prompt)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("run %s: %w", b.cli.name, ctx.Err())
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("run %s: %w: %s", b.cli.name, err, detail)
+		}
+		return "", fmt.Errorf("run %s: %w", b.cli.name, err)
+	}
+
+	return b.adapter.clean(stdout.String()), nil
+}
+
+func argsReference(args []string, placeholder string) bool {
+	for _, arg := range args {
+		if strings.Contains(arg, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+var selectBackendFn = defaultSelectBackend
+
+func defaultSelectBackend(configDir string, opts backendOptions) (llmBackend, error) {
+	backend, err := selectBaseBackend(configDir, opts.cli)
+	if err != nil {
+		return nil, err
+	}
+	if cli, ok := backend.(cliBackend); ok && !opts.noCache {
+		cache := newResponseCache(configDir, opts.cacheTTL)
+		logVerbose("caching %s responses in %s (ttl %s)", backend.name(), cache.dir, cache.ttl)
+		backend = cachingBackend{inner: backend, version: cli.adapter.Version, cache: cache}
+	}
+	if opts.record {
+		dir, err := fixturesDir(configDir)
+		if err != nil {
+			return nil, err
+		}
+		logVerbose("recording %s responses into %s", backend.name(), dir)
+		backend = recordingBackend{inner: backend, dir: dir}
+	}
+	return backend, nil
+}
+
+func selectBaseBackend(configDir, name string) (llmBackend, error) {
+	if name == "" {
+		if raw := strings.TrimSpace(os.Getenv(envCLIBinary)); isBuiltinBackend(raw) {
+			name = raw
+		}
+	}
+	if isBuiltinBackend(name) {
+		logVerbose("using built-in backend %s", name)
+		return builtinBackend(configDir, name)
+	}
+
+	reg, err := loadAdapters(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var cli detectedCLI
+	var adapter cliAdapter
+	if name != "" {
+		found, ok := lookupCLI(name, reg.binary(name))
+		if !ok {
+			return nil, fmt.Errorf("CLI %s not found on PATH", reg.binary(name))
+		}
+		cli, adapter = found, reg.lookup(name)
+	} else {
+		cli, adapter, err = reg.require()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !adapter.Capabilities.NonInteractive {
+		return nil, fmt.Errorf("adapter %s does not declare capabilities.non_interactive", adapter.Name)
+	}
+	logVerbose("using adapter %s v%s (prompt via %s)", adapter.Name, adapter.Version, adapter.Prompt)
+	return cliBackend{cli: cli, adapter: adapter}, nil
+}
+
+func cliTimeout() (time.Duration, error) {
+	raw := strings.TrimSpace(os.Getenv(envCLITimeout))
+	if raw == "" {
+		return defaultCLITimeout, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", envCLITimeout, raw, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be positive", envCLITimeout, raw)
+	}
+	return d, nil
+}
+
+type refiner struct {
+	backend llmBackend
+	timeout time.Duration
+	// strict refiners were asked for a backend by name with --cli, so a
+	// failure is an error instead of a fallback to the raw render.
+	strict bool
+}
+
+func newRefiner(configDir string, opts backendOptions) (*refiner, error) {
+	timeout, err := cliTimeout()
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := selectBackendFn(configDir, opts)
+	if err != nil && opts.cli != "" {
+		return nil, fmt.Errorf("--cli %s: %w", opts.cli, err)
+	}
+	if err != nil {
+		logWarning("refinement disabled, writing raw renders: %v", err)
+		return nil, nil
+	}
+	logVerbose("refining outputs via %s (timeout %s)", backend.name(), timeout)
+	return &refiner{backend: backend, timeout: timeout, strict: opts.cli != ""}, nil
+}
+
+func (r *refiner) refine(file, prompt string) (string, error) {
+	if r == nil {
+		return prompt, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	out, err := r.backend.complete(ctx, prompt)
+	if err == nil && strings.TrimSpace(out) == "" {
+		err = fmt.Errorf("%s returned no output", r.backend.name())
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%s timed out after %s (raise %s to wait longer)", r.backend.name(), r.timeout, envCLITimeout)
+	}
+	if err != nil && r.strict {
+		return "", fmt.Errorf("%s: refine: %w", file, err)
+	}
+	if err != nil {
+		logWarning("%s: refinement failed, writing raw render: %v", file, err)
+		return prompt, nil
+	}
+
+	logVerbose("refined %s via %s (%d bytes)", file, r.backend.name(), len(out))
+	return strings.TrimSpace(out) + "\n", nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFakeCLI(t *testing.T, name, script string) detectedCLI {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("write fake %s: %v", name, err)
+	}
+	return detectedCLI{name: name, path: path}
+}
+
+func captureWarnings(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	warningLogger.SetOutput(&buf)
+	t.Cleanup(func() { warningLogger.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestCLITimeout(t *testing.T) {
+	t.Setenv(envCLITimeout, "")
+	if d, err := cliTimeout(); err != nil || d != defaultCLITimeout {
+		t.Fatalf("cliTimeout default = %v, %v", d, err)
+	}
+
+	t.Setenv(envCLITimeout, "90s")
+	if d, err := cliTimeout(); err != nil || d != 90*time.Second {
+		t.Fatalf("cliTimeout = %v, %v; want 90s", d, err)
+	}
+
+	for _, bad := range []string{"soon", "-1s", "0"} {
+		t.Setenv(envCLITimeout, bad)
+		if _, err := cliTimeout(); err == nil || !strings.Contains(err.Error(), envCLITimeout) {
+			t.Fatalf("cliTimeout(%q) error = %v", bad, err)
+		}
+	}
+}
+
+func TestRefinerPipesPromptThroughCLI(t *testing.T) {
+	cli := writeFakeCLI(t, "codex", "tr a-z A-Z")
+	r := &refiner{backend: cliBackend{cli: cli, adapter: genericAdapter}, timeout: 5 * time.Second}
+
+	got, err := r.refine("PRD.md", "ship it\n")
+	if err != nil || got != "SHIP IT\n" {
+		t.Fatalf("refine = %q, want SHIP IT", got)
+	}
+}
+
+func TestRefinerFallsBackOnFailure(t *testing.T) {
+	warnings := captureWarnings(t)
+	cli := writeFakeCLI(t, "codex", "echo boom >&2\nexit 3")
+	r := &refiner{backend: cliBackend{cli: cli, adapter: genericAdapter}, timeout: 5 * time.Second}
+
+	got, err := r.refine("PRD.md", "raw prompt")
+	if err != nil || got != "raw prompt" {
+		t.Fatalf("refine = %q, want raw prompt", got)
+	}
+	if !strings.Contains(warnings.String(), "PRD.md: refinement failed") || !strings.Contains(warnings.String(), "boom") {
+		t.Fatalf("missing failure warning: %s", warnings.String())
+	}
+}
+
+func TestRefinerFallsBackOnTimeout(t *testing.T) {
+	warnings := captureWarnings(t)
+	cli := writeFakeCLI(t, "codex", "exec sleep 5")
+	r := &refiner{backend: cliBackend{cli: cli, adapter: genericAdapter}, timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	got, err := r.refine("PLAN.md", "raw prompt")
+	if err != nil || got != "raw prompt" {
+		t.Fatalf("refine = %q, want raw prompt", got)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("refine did not honor timeout")
+	}
+	if !strings.Contains(warnings.String(), "timed out after 50ms") {
+		t.Fatalf("missing timeout warning: %s", warnings.String())
+	}
+}
+
+func TestRefinerFallsBackOnEmptyOutput(t *testing.T) {
+	warnings := captureWarnings(t)
+	cli := writeFakeCLI(t, "codex", "cat >/dev/null")
+	r := &refiner{backend: cliBackend{cli: cli, adapter: genericAdapter}, timeout: 5 * time.Second}
+
+	if got, err := r.refine("PRD.md", "raw prompt"); err != nil || got != "raw prompt" {
+		t.Fatalf("refine = %q, want raw prompt", got)
+	}
+	if !strings.Contains(warnings.String(), "returned no output") {
+		t.Fatalf("missing empty-output warning: %s", warnings.String())
+	}
+}
+
+func TestNewRefinerWithoutCLIDisablesRefinement(t *testing.T) {
+	warnings := captureWarnings(t)
+	t.Setenv("PATH", "")
+	t.Setenv(envCLIBinary, "")
+	t.Setenv(envCLITimeout, "")
+
+	r, err := newRefiner(t.TempDir(), backendOptions{})
+	if err != nil {
+		t.Fatalf("newRefiner returned error: %v", err)
+	}
+	if r != nil {
+		t.Fatalf("expected nil refiner without CLI")
+	}
+	if got, err := r.refine("PRD.md", "raw"); err != nil || got != "raw" {
+		t.Fatalf("nil refiner should pass prompt through, got %q", got)
+	}
+	if !strings.Contains(warnings.String(), "refinement disabled") {
+		t.Fatalf("missing disabled warning: %s", warnings.String())
+	}
+}
+
+func TestExplicitCLIFailuresAreErrors(t *testing.T) {
+	t.Setenv("PATH", "")
+	t.Setenv(envCLIBinary, "")
+	t.Setenv(envCLITimeout, "")
+
+	if _, err := newRefiner(t.TempDir(), backendOptions{cli: "codex"}); err == nil || !strings.Contains(err.Error(), "--cli codex") {
+		t.Fatalf("newRefiner error = %v, want an error for a missing --cli", err)
+	}
+
+	t.Setenv(envFixturesDir, t.TempDir())
+	r, err := newRefiner(t.TempDir(), backendOptions{cli: fixtureBackendName})
+	if err != nil {
+		t.Fatalf("newRefiner: %v", err)
+	}
+	if _, err := r.refine("PRD.md", "no fixture for this"); err == nil || !strings.Contains(err.Error(), "PRD.md: refine") {
+		t.Fatalf("refine error = %v, want a missing fixture error", err)
+	}
+}
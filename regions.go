@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Managed regions are delimited by marker comments in templates:
+//
+//	<!-- beet:begin tasks -->
+//	...
+//	<!-- beet:end tasks -->
+//
+// When an output already exists, regeneration replaces only the regions and
+// keeps everything written outside them. Markers inside fenced code blocks,
+// such as examples quoted in an intent, are plain text.
+var regionMarkerPattern = regexp.MustCompile(`^\s*<!--\s*beet:(begin|end)\s+([A-Za-z0-9_.-]+)\s*-->\s*$`)
+
+// regionSegment is either free text (name == "") or one managed region,
+// markers included.
+type regionSegment struct {
+	name string
+	text string
+}
+
+func parseRegions(text string) ([]regionSegment, error) {
+	var segments []regionSegment
+	var current strings.Builder
+	open, openLine := "", 0
+	seen := make(map[string]bool)
+	var fence codeFence
+
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		var m []string
+		if !fence.update(line) {
+			m = regionMarkerPattern.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		}
+		switch {
+		case m == nil:
+			current.WriteString(line)
+		case m[1] == "begin":
+			if open != "" {
+				return nil, fmt.Errorf("line %d: region %s begins inside region %s opened at line %d", i+1, m[2], open, openLine)
+			}
+			if seen[m[2]] {
+				return nil, fmt.Errorf("line %d: duplicate region %s", i+1, m[2])
+			}
+			if current.Len() > 0 {
+				segments = append(segments, regionSegment{text: current.String()})
+				current.Reset()
+			}
+			open, openLine = m[2], i+1
+			seen[open] = true
+			current.WriteString(line)
+		default:
+			if open != m[2] {
+				return nil, fmt.Errorf("line %d: end of region %s without a matching begin", i+1, m[2])
+			}
+			current.WriteString(line)
+			segments = append(segments, regionSegment{name: open, text: current.String()})
+			current.Reset()
+			open = ""
+		}
+	}
+	if open != "" {
+		return nil, fmt.Errorf("line %d: region %s is never closed", openLine, open)
+	}
+	if current.Len() > 0 {
+		segments = append(segments, regionSegment{text: current.String()})
+	}
+	return segments, nil
+}
+
+// codeFence follows fenced code blocks line by line the way CommonMark does:
+// a fence is three or more backticks or tildes indented at most three
+// spaces, and only a bare run of the same character at least as long closes
+// it.
+type codeFence struct {
+	char byte
+	size int
+}
+
+// update feeds the next line to f and reports whether it belongs to a fenced
+// block, the fence lines included.
+func (f *codeFence) update(line string) bool {
+	char, size, rest, ok := fenceRun(line)
+	if f.size == 0 {
+		// Backtick fences can't have backticks in their info string, so
+		// inline code such as ```x``` opens nothing.
+		if !ok || (char == '`' && strings.Contains(rest, "`")) {
+			return false
+		}
+		f.char, f.size = char, size
+		return true
+	}
+	if ok && char == f.char && size >= f.size && strings.TrimSpace(rest) == "" {
+		f.size = 0
+	}
+	return true
+}
+
+func fenceRun(line string) (char byte, size int, rest string, ok bool) {
+	line = strings.TrimRight(line, "\r\n")
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || trimmed == "" || (trimmed[0] != '`' && trimmed[0] != '~') {
+		return 0, 0, "", false
+	}
+	char = trimmed[0]
+	for size < len(trimmed) && trimmed[size] == char {
+		size++
+	}
+	if size < 3 {
+		return 0, 0, "", false
+	}
+	return char, size, trimmed[size:], true
+}
+
+func hasRegions(segments []regionSegment) bool {
+	for _, s := range segments {
+		if s.name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeRegions updates the regions of existing with those of rendered. Text
+// outside regions and regions the template no longer defines are kept; new
+// regions are placed after the region that precedes them in the template.
+func mergeRegions(existing []regionSegment, rendered []regionSegment) string {
+	fresh := make(map[string]string)
+	for _, s := range rendered {
+		if s.name != "" {
+			fresh[s.name] = s.text
+		}
+	}
+
+	out := make([]regionSegment, 0, len(existing))
+	for _, s := range existing {
+		if text, ok := fresh[s.name]; ok && s.name != "" {
+			s.text = text
+			delete(fresh, s.name)
+		} else if s.name != "" {
+			logVerbose("keeping region %s, which the template no longer defines", s.name)
+		}
+		out = append(out, s)
+	}
+
+	previous := ""
+	for _, s := range rendered {
+		if s.name == "" {
+			continue
+		}
+		if _, missing := fresh[s.name]; missing {
+			at := regionIndex(out, previous) + 1
+			if previous == "" {
+				at = regionIndex(out, firstRegion(out))
+				if at < 0 {
+					at = len(out)
+				}
+			}
+			out = append(out[:at], append([]regionSegment{{name: s.name, text: ensureTrailingNewline(s.text)}}, out[at:]...)...)
+		}
+		previous = s.name
+	}
+
+	var b strings.Builder
+	for i, s := range out {
+		text := s.text
+		if i < len(out)-1 {
+			text = ensureTrailingNewline(text)
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+func regionIndex(segments []regionSegment, name string) int {
+	for i, s := range segments {
+		if name != "" && s.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func firstRegion(segments []regionSegment) string {
+	for _, s := range segments {
+		if s.name != "" {
+			return s.name
+		}
+	}
+	return ""
+}
+
+func ensureTrailingNewline(text string) string {
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		return text + "\n"
+	}
+	return text
+}
+
+// mergeExistingOutput merges content into existing, the current file at path,
+// when both use managed regions. ok is false when there is nothing to merge.
+func mergeExistingOutput(path, existing, content string) (string, bool, error) {
+	rendered, err := parseRegions(content)
+	if err != nil {
+		return "", false, fmt.Errorf("render %s: managed regions: %w", path, err)
+	}
+	if !hasRegions(rendered) {
+		return "", false, nil
+	}
+	current, err := parseRegions(existing)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: managed regions: %w; fix the markers or remove the file to regenerate it", path, err)
+	}
+	if !hasRegions(current) {
+		return "", false, nil
+	}
+	logVerbose("updating managed regions in %s", path)
+	return mergeRegions(current, rendered), true, nil
+}
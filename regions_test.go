@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRegionsErrors(t *testing.T) {
+	cases := map[string]string{
+		"<!-- beet:begin a -->\n<!-- beet:begin b -->\n": "region b begins inside region a",
+		"<!-- beet:end a -->\n":                          "end of region a without a matching begin",
+		"<!-- beet:begin a -->\nx\n":                     "region a is never closed",
+		"<!-- beet:begin a -->\n<!-- beet:end a -->\n<!-- beet:begin a -->\n<!-- beet:end a -->\n": "duplicate region a",
+	}
+	for text, want := range cases {
+		if _, err := parseRegions(text); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("parseRegions(%q): expected %q, got %v", text, want, err)
+		}
+	}
+}
+
+func TestParseRegionsIgnoresFencedMarkers(t *testing.T) {
+	text := "# Intent\n```md\n<!-- beet:begin tasks -->\n```\n<!-- beet:begin a -->\n~~~\n<!-- beet:end a -->\n~~~\n<!-- beet:end a -->\n"
+	segments, err := parseRegions(text)
+	if err != nil {
+		t.Fatalf("parseRegions: %v", err)
+	}
+	if len(segments) != 2 || segments[1].name != "a" || !strings.HasPrefix(segments[1].text, "<!-- beet:begin a -->\n~~~") {
+		t.Fatalf("segments = %+v", segments)
+	}
+}
+
+func TestParseRegionsStrayFences(t *testing.T) {
+	// Inline ```code```, a shorter run inside a longer fence and a closer
+	// with an info string must not hide the end marker.
+	intent := "Use ```go test``` first.\n````md\n```\n<!-- beet:end task -->\n```\n````\n~~~\n~~~ not a closer\n~~~\n"
+	text := "# Prompt\n<!-- beet:begin task -->\n" + intent + "<!-- beet:end task -->\n"
+	segments, err := parseRegions(text)
+	if err != nil {
+		t.Fatalf("parseRegions: %v", err)
+	}
+	if len(segments) != 2 || segments[1].name != "task" || !strings.HasSuffix(segments[1].text, "~~~\n<!-- beet:end task -->\n") {
+		t.Fatalf("segments = %+v", segments)
+	}
+	if _, _, err := mergeExistingOutput("WORK_PROMPT.md", text, text); err != nil {
+		t.Fatalf("merge with itself: %v", err)
+	}
+}
+
+func TestMergeRegions(t *testing.T) {
+	existing := "# Edited title\n\n<!-- beet:begin b -->\nold b\n<!-- beet:end b -->\nnotes\n<!-- beet:begin gone -->\nkept\n<!-- beet:end gone -->\n"
+	rendered := "# Title\n<!-- beet:begin a -->\nnew a\n<!-- beet:end a -->\n<!-- beet:begin b -->\nnew b\n<!-- beet:end b -->\n<!-- beet:begin c -->\nnew c\n<!-- beet:end c -->\n"
+
+	old, err := parseRegions(existing)
+	if err != nil {
+		t.Fatalf("parse existing: %v", err)
+	}
+	fresh, err := parseRegions(rendered)
+	if err != nil {
+		t.Fatalf("parse rendered: %v", err)
+	}
+
+	want := "# Edited title\n\n" +
+		"<!-- beet:begin a -->\nnew a\n<!-- beet:end a -->\n" +
+		"<!-- beet:begin b -->\nnew b\n<!-- beet:end b -->\n" +
+		"<!-- beet:begin c -->\nnew c\n<!-- beet:end c -->\n" +
+		"notes\n<!-- beet:begin gone -->\nkept\n<!-- beet:end gone -->\n"
+	if got := mergeRegions(old, fresh); got != want {
+		t.Fatalf("mergeRegions =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteRenderedOutputKeepsHandEdits(t *testing.T) {
+	dir := t.TempDir()
+	plan := filepath.Join(dir, "PLAN.md")
+	if _, err := writeRenderedOutput(plan, "# Plan\n<!-- beet:begin plan -->\n- [ ] one\n<!-- beet:end plan -->\n", onExistsDefault); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	if err := os.WriteFile(plan, []byte("# Plan\n<!-- beet:begin plan -->\n- [ ] one\n<!-- beet:end plan -->\nmine\n"), 0o644); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if _, err := writeRenderedOutput(plan, "# Plan\n<!-- beet:begin plan -->\n- [ ] two\n<!-- beet:end plan -->\n", onExistsDefault); err != nil {
+		t.Fatalf("second write: %v", err)
+	}
+	got, _ := os.ReadFile(plan)
+	if string(got) != "# Plan\n<!-- beet:begin plan -->\n- [ ] two\n<!-- beet:end plan -->\nmine\n" {
+		t.Fatalf("PLAN.md = %q", got)
+	}
+
+	if err := os.WriteFile(plan, []byte("<!-- beet:begin plan -->\nbroken\n"), 0o644); err != nil {
+		t.Fatalf("break markers: %v", err)
+	}
+	if _, err := writeRenderedOutput(plan, "<!-- beet:begin plan -->\nx\n<!-- beet:end plan -->\n", onExistsDefault); err == nil || !strings.Contains(err.Error(), "region plan is never closed") {
+		t.Fatalf("expected marker error, got %v", err)
+	}
+}
+
+func TestWriteRenderedOutputMergePolicy(t *testing.T) {
+	warnings := captureWarnings(t)
+	dir := t.TempDir()
+	agents := filepath.Join(dir, agentsFilename)
+	rendered := "## Agents\n<!-- beet:begin guidelines -->\nnew rules\n<!-- beet:end guidelines -->\n"
+
+	if err := os.WriteFile(agents, []byte("hand written\n"), 0o644); err != nil {
+		t.Fatalf("write agents: %v", err)
+	}
+	if _, err := writeRenderedOutput(agents, rendered, onExistsMerge); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got, _ := os.ReadFile(agents); string(got) != "hand written\n" {
+		t.Fatalf("merge without regions should leave the file alone, got %q", got)
+	}
+	if !strings.Contains(warnings.String(), "no managed regions to merge") {
+		t.Fatalf("warnings = %q", warnings.String())
+	}
+
+	existing := "## Agents\nlocal notes\n<!-- beet:begin guidelines -->\nold rules\n<!-- beet:end guidelines -->\n"
+	if err := os.WriteFile(agents, []byte(existing), 0o644); err != nil {
+		t.Fatalf("write agents: %v", err)
+	}
+	if _, err := writeRenderedOutput(agents, rendered, onExistsMerge); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got, _ := os.ReadFile(agents); string(got) != "## Agents\nlocal notes\n<!-- beet:begin guidelines -->\nnew rules\n<!-- beet:end guidelines -->\n" {
+		t.Fatalf("regions not updated: %q", got)
+	}
+
+	if _, err := writeRenderedOutput(agents, rendered, onExistsOverwrite); err != nil {
+		t.Fatalf("forced write: %v", err)
+	}
+	if got, _ := os.ReadFile(agents); string(got) != rendered {
+		t.Fatalf("overwrite should replace the file, got %q", got)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	scaffoldPartialName = "intent-scaffold"
+	lastIntentFilename  = "last-intent.md"
+)
+
+var (
+	commentLinePattern = regexp.MustCompile(`(?ms)^[ \t]*<!--.*?-->[ \t]*(?:\n|\z)`)
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// intentScaffold renders the editor buffer for a pack: a section heading for
+// every structured placeholder the pack's templates use, and the last intent
+// for reference, all inside comments that stripScaffold removes again.
+// tmplName is the -t override for WORK_PROMPT.md, if any.
+func intentScaffold(configDir, packName string, p pack, tmplName string) (string, error) {
+	text, err := loadScaffold(configDir)
+	if err != nil {
+		return "", err
+	}
+	used, err := packPlaceholderNames(configDir, p, tmplName)
+	if err != nil {
+		return "", err
+	}
+
+	values := placeholderValues{
+		"pack":        strings.TrimSuffix(normalizePackName(packName), ".yaml"),
+		"last_intent": strings.ReplaceAll(loadLastIntent(configDir), "-->", "->"),
+	}
+	for name := range used {
+		if _, ok := sectionPlaceholder(name); ok {
+			values["section."+name] = "true"
+		}
+	}
+
+	rendered, _, err := renderTemplateText(scaffoldPartialName+".md", text, values, partialsFrom(configDir))
+	if err != nil {
+		return "", err
+	}
+	return rendered, nil
+}
+
+// loadScaffold prefers a user or project partial and falls back to the
+// bundled one, so configs bootstrapped before the scaffold existed still work.
+func loadScaffold(configDir string) (string, error) {
+	text, err := loadPartial(configDir, scaffoldPartialName)
+	if err == nil {
+		return text, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	b, err := embeddedDefaults.ReadFile(path.Join("defaults", partialsDirName, scaffoldPartialName+".md"))
+	if err != nil {
+		return "", fmt.Errorf("load partial %s: %w", scaffoldPartialName, err)
+	}
+	return string(b), nil
+}
+
+func packPlaceholderNames(configDir string, p pack, tmplName string) (map[string]bool, error) {
+	names := make(map[string]bool)
+	for _, out := range p.Outputs {
+		templateName := out.Template
+		if tmplName != "" && strings.EqualFold(out.File, workPromptFilename) {
+			templateName = tmplName
+		}
+		text, err := loadTemplate(configDir, templateName)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := parseTemplate(templateName, text, partialsFrom(configDir))
+		if err != nil {
+			return nil, err
+		}
+		collectPlaceholderNames(parsed.nodes, names)
+	}
+	return names, nil
+}
+
+func collectPlaceholderNames(nodes []templateNode, names map[string]bool) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case varNode:
+			names[n.name] = true
+		case *ifNode:
+			names[n.name] = true
+			collectPlaceholderNames(n.then, names)
+			collectPlaceholderNames(n.otherwise, names)
+		case *eachNode:
+			names[n.name] = true
+			collectPlaceholderNames(n.body, names)
+			collectPlaceholderNames(n.otherwise, names)
+		}
+	}
+}
+
+// stripScaffold removes comments, like git does for commit messages, and
+// drops headings whose sections were left empty.
+func stripScaffold(text string) string {
+	text = commentLinePattern.ReplaceAllString(strings.ReplaceAll(text, "\r\n", "\n"), "")
+	text = htmlCommentPattern.ReplaceAllString(text, "")
+	lines := strings.Split(text, "\n")
+
+	var out []string
+	var fence codeFence
+	for i, line := range lines {
+		if !fence.update(line) {
+			if m := headingPattern.FindStringSubmatch(line); m != nil && emptySection(lines[i+1:], len(m[1])) {
+				continue
+			}
+		}
+		out = append(out, strings.TrimRight(line, " \t"))
+	}
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(strings.Join(out, "\n"), "\n\n"))
+}
+
+func emptySection(rest []string, level int) bool {
+	for _, line := range rest {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m := headingPattern.FindStringSubmatch(line)
+		return m != nil && len(m[1]) <= level
+	}
+	return true
+}
+
+func loadLastIntent(configDir string) string {
+	b, err := os.ReadFile(filepath.Join(configDir, lastIntentFilename))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func saveLastIntent(configDir, intent string) error {
+	if err := writeFileAtomic(filepath.Join(configDir, lastIntentFilename), []byte(intent+"\n")); err != nil {
+		return fmt.Errorf("save last intent: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntentScaffoldListsPackSections(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	if err := bootstrapDefaults(configDir); err != nil {
+		t.Fatalf("bootstrapDefaults: %v", err)
+	}
+	writeConfigFile(t, configDir, "templates/t.md", "{{intent}}\n{{goals}}\n{{#if acceptance_criteria}}{{acceptance_criteria}}{{/if}}\n{{audience}}\n")
+	writeConfigFile(t, configDir, "packs/p.yaml", "outputs:\n  - file: OUT.md\n    template: t.md\n")
+	if err := saveLastIntent(configDir, "ship it --> now"); err != nil {
+		t.Fatalf("saveLastIntent: %v", err)
+	}
+
+	p, err := loadPack(configDir, "p")
+	if err != nil {
+		t.Fatalf("loadPack: %v", err)
+	}
+	got, err := intentScaffold(configDir, "p", p, "")
+	if err != nil {
+		t.Fatalf("intentScaffold: %v", err)
+	}
+	for _, want := range []string{"for the p pack", "## Goals\n<!-- What does success look like? -->", "## Acceptance Criteria", "ship it -> now\n-->"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("scaffold missing %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"## Requirements", "## Risks", "## Audience", "## Intent"} {
+		if strings.Contains(got, unwanted) {
+			t.Fatalf("scaffold should not contain %q:\n%s", unwanted, got)
+		}
+	}
+	if stripped := stripScaffold(got); stripped != "" {
+		t.Fatalf("untouched scaffold should strip to nothing, got %q", stripped)
+	}
+
+	if err := os.Remove(filepath.Join(configDir, "partials", "intent-scaffold.md")); err != nil {
+		t.Fatalf("remove partial: %v", err)
+	}
+	if _, err := intentScaffold(configDir, "p", p, ""); err != nil {
+		t.Fatalf("intentScaffold without partial: %v", err)
+	}
+}
+
+func TestStripScaffold(t *testing.T) {
+	text := "<!-- hint -->\nAdd export <!-- inline -->now.\n\n```\n## kept in fence\n```\n<!-- multi\nline -->\n## Goals\n<!-- What? -->\n- CSV\n\n## Risks\n<!-- What could go wrong? -->\n\n"
+	want := "Add export now.\n\n```\n## kept in fence\n```\n## Goals\n- CSV"
+	if got := stripScaffold(text); got != want {
+		t.Fatalf("stripScaffold = %q, want %q", got, want)
+	}
+}
+
+func TestIntentFromDefaultAppWaitsPastScaffold(t *testing.T) {
+	origOpen := defaultBrowser
+	origWait := waitForContentFn
+	t.Cleanup(func() {
+		defaultBrowser = origOpen
+		waitForContentFn = origWait
+	})
+
+	scaffold := "<!-- describe it -->\n## Goals\n"
+	defaultBrowser = browserFake(func(path string) error {
+		b, err := os.ReadFile(path)
+		if err != nil || string(b) != scaffold {
+			t.Fatalf("buffer = %q, %v; want scaffold", b, err)
+		}
+		return os.WriteFile(path, []byte(scaffold+"- fast\n"), 0o644)
+	})
+	waitForContentFn = func(_ string, initial string) error {
+		if initial != scaffold {
+			t.Fatalf("wait initial = %q, want scaffold", initial)
+		}
+		return nil
+	}
+	t.Setenv("EDITOR", "")
+
+	intent, err := intentFromEditor(scaffold)
+	if err != nil {
+		t.Fatalf("intentFromEditor: %v", err)
+	}
+	if intent != "## Goals\n- fast" {
+		t.Fatalf("intent = %q", intent)
+	}
+}
+
+func TestHandleGenerateSavesLastIntent(t *testing.T) {
+	configDir := filepath.Join(t.TempDir(), "cfg")
+	if err := ensureConfigStructure(configDir); err != nil {
+		t.Fatalf("ensureConfigStructure: %v", err)
+	}
+	if err := bootstrapDefaults(configDir); err != nil {
+		t.Fatalf("bootstrapDefaults: %v", err)
+	}
+	t.Chdir(t.TempDir())
+
+	if err := handleGenerate(configDir, []string{"--dry-run", "preview", "only"}); err != nil {
+		t.Fatalf("handleGenerate: %v", err)
+	}
+	if got := loadLastIntent(configDir); got != "" {
+		t.Fatalf("dry run saved last intent %q", got)
+	}
+	if err := handleDiff(configDir, []string{"--stat", "preview", "only"}); err != nil {
+		t.Fatalf("handleDiff: %v", err)
+	}
+	if got := loadLastIntent(configDir); got != "" {
+		t.Fatalf("diff saved last intent %q", got)
+	}
+
+	if err := handleGenerate(configDir, []string{"remember", "me"}); err != nil {
+		t.Fatalf("handleGenerate: %v", err)
+	}
+	if got := loadLastIntent(configDir); got != "remember me" {
+		t.Fatalf("last intent = %q", got)
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	templateTagPattern     = regexp.MustCompile(`\{\{(.*?)\}\}`)
+	templateVarPattern     = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*|\.)$`)
+	templatePartialPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*(/[A-Za-z0-9_][A-Za-z0-9_.-]*)*$`)
+	templatePipePattern    = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z_][A-Za-z0-9_]*)*|\.)\s*\|\s*(\w+)\s*(.*)$`)
+	listBulletPattern      = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+`)
+)
+
+type templateNode interface{}
+
+type textNode struct {
+	text string
+}
+
+type varNode struct {
+	name       string
+	fallback   string
+	hasDefault bool
+}
+
+type ifNode struct {
+	name      string
+	then      []templateNode
+	otherwise []templateNode
+}
+
+type eachNode struct {
+	name      string
+	body      []templateNode
+	otherwise []templateNode
+}
+
+type parsedTemplate struct {
+	name  string
+	nodes []templateNode
+}
+
+type templateToken struct {
+	text       string
+	raw        string
+	tag        string
+	isTag      bool
+	standalone bool
+	line       int
+}
+
+type partialLoader func(name string) (string, error)
+
+type templateParser struct {
+	load  partialLoader
+	stack []string
+}
+
+type openBlock struct {
+	kind     string
+	name     string
+	line     int
+	children *[]templateNode
+	node     templateNode
+	inElse   bool
+}
+
+func parseTemplate(name, text string, load partialLoader) (*parsedTemplate, error) {
+	p := &templateParser{load: load}
+	nodes, err := p.parse(name, text)
+	if err != nil {
+		return nil, err
+	}
+	return &parsedTemplate{name: name, nodes: nodes}, nil
+}
+
+func (p *templateParser) parse(name, text string) ([]templateNode, error) {
+	p.stack = append(p.stack, name)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+
+	tokens := tokenizeTemplate(text)
+
+	root := []templateNode{}
+	var stack []*openBlock
+	current := &root
+
+	for _, tok := range tokens {
+		if !tok.isTag {
+			if tok.text != "" {
+				*current = append(*current, textNode{text: tok.text})
+			}
+			continue
+		}
+
+		tag := strings.TrimSpace(tok.tag)
+		switch {
+		case strings.HasPrefix(tag, ">"):
+			partialName, partialText, err := p.include(name, tok, tag)
+			if err != nil {
+				return nil, err
+			}
+			nodes, err := p.parse(partialName, partialText)
+			if err != nil {
+				return nil, err
+			}
+			*current = append(*current, nodes...)
+		case strings.HasPrefix(tag, "#"):
+			fields := strings.Fields(tag[1:])
+			if len(fields) == 0 {
+				return nil, templateError(name, tok.line, "empty block tag {{%s}}", tok.tag)
+			}
+			kind := fields[0]
+			if kind != "if" && kind != "each" {
+				return nil, templateError(name, tok.line, "unknown block {{#%s}}", kind)
+			}
+			if len(fields) != 2 || !templateVarPattern.MatchString(fields[1]) {
+				return nil, templateError(name, tok.line, "{{#%s}} needs exactly one placeholder name", kind)
+			}
+
+			block := &openBlock{kind: kind, name: fields[1], line: tok.line}
+			var children *[]templateNode
+			switch kind {
+			case "if":
+				n := &ifNode{name: fields[1]}
+				block.node = n
+				children = &n.then
+			case "each":
+				n := &eachNode{name: fields[1]}
+				block.node = n
+				children = &n.body
+			}
+			*current = append(*current, block.node)
+			block.children = children
+			stack = append(stack, block)
+			current = children
+		case tag == "else":
+			if len(stack) == 0 {
+				return nil, templateError(name, tok.line, "{{else}} outside of a block")
+			}
+			top := stack[len(stack)-1]
+			if top.inElse {
+				return nil, templateError(name, tok.line, "duplicate {{else}} in {{#%s %s}} opened at line %d", top.kind, top.name, top.line)
+			}
+			top.inElse = true
+			switch n := top.node.(type) {
+			case *ifNode:
+				current = &n.otherwise
+			case *eachNode:
+				current = &n.otherwise
+			}
+		case strings.HasPrefix(tag, "/"):
+			kind := strings.TrimSpace(tag[1:])
+			if len(stack) == 0 {
+				return nil, templateError(name, tok.line, "unexpected {{/%s}}", kind)
+			}
+			top := stack[len(stack)-1]
+			if kind != top.kind {
+				return nil, templateError(name, tok.line, "{{/%s}} does not close {{#%s %s}} opened at line %d", kind, top.kind, top.name, top.line)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				current = &root
+			} else {
+				parent := stack[len(stack)-1]
+				current = parent.children
+				if parent.inElse {
+					switch n := parent.node.(type) {
+					case *ifNode:
+						current = &n.otherwise
+					case *eachNode:
+						current = &n.otherwise
+					}
+				}
+			}
+		default:
+			node, ok, err := parseVarTag(tag)
+			if err != nil {
+				return nil, templateError(name, tok.line, "%v", err)
+			}
+			if !ok {
+				*current = append(*current, textNode{text: "{{" + tok.tag + "}}"})
+				continue
+			}
+			*current = append(*current, node)
+		}
+	}
+
+	if len(stack) > 0 {
+		top := stack[len(stack)-1]
+		return nil, templateError(name, top.line, "unclosed {{#%s %s}}", top.kind, top.name)
+	}
+
+	return root, nil
+}
+
+func (p *templateParser) include(name string, tok templateToken, tag string) (string, string, error) {
+	target := strings.TrimSpace(strings.TrimPrefix(tag, ">"))
+	if !templatePartialPattern.MatchString(target) || strings.Contains(target, "..") {
+		return "", "", templateError(name, tok.line, "invalid partial name in {{%s}}", tok.tag)
+	}
+
+	partialName := filepath.ToSlash(filepath.Join(partialsDirName, normalizeTemplateName(target)))
+	for _, open := range p.stack {
+		if open == partialName {
+			chain := append(append([]string(nil), p.stack...), partialName)
+			return "", "", templateError(name, tok.line, "include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	if p.load == nil {
+		return "", "", templateError(name, tok.line, "partial %s unavailable", target)
+	}
+	text, err := p.load(target)
+	if err != nil {
+		return "", "", templateError(name, tok.line, "%v", err)
+	}
+	if tok.standalone && text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return partialName, text, nil
+}
+
+func expandTemplate(name, text string, load partialLoader) (string, error) {
+	p := &templateParser{load: load}
+	return p.expand(name, text)
+}
+
+func (p *templateParser) expand(name, text string) (string, error) {
+	p.stack = append(p.stack, name)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+
+	var b strings.Builder
+	for _, tok := range tokenizeTemplate(text) {
+		tag := strings.TrimSpace(tok.tag)
+		if !tok.isTag || !strings.HasPrefix(tag, ">") {
+			b.WriteString(tok.raw)
+			continue
+		}
+		partialName, partialText, err := p.include(name, tok, tag)
+		if err != nil {
+			return "", err
+		}
+		expanded, err := p.expand(partialName, partialText)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(expanded)
+	}
+	return b.String(), nil
+}
+
+func parseVarTag(tag string) (templateNode, bool, error) {
+	if templateVarPattern.MatchString(tag) {
+		return varNode{name: tag}, true, nil
+	}
+
+	m := templatePipePattern.FindStringSubmatch(tag)
+	if m == nil {
+		return nil, false, nil
+	}
+	if m[2] != "default" {
+		return nil, false, fmt.Errorf("unknown filter %q in {{%s}}", m[2], tag)
+	}
+	fallback, err := strconv.Unquote(strings.TrimSpace(m[3]))
+	if err != nil {
+		return nil, false, fmt.Errorf("default in {{%s}} needs a quoted string", tag)
+	}
+	return varNode{name: m[1], fallback: fallback, hasDefault: true}, true, nil
+}
+
+func templateError(name string, line int, format string, args ...interface{}) error {
+	return fmt.Errorf("template %s:%d: %s", name, line, fmt.Sprintf(format, args...))
+}
+
+func isBlockTag(tag string) bool {
+	tag = strings.TrimSpace(tag)
+	return strings.HasPrefix(tag, "#") || strings.HasPrefix(tag, "/") || strings.HasPrefix(tag, ">") || tag == "else"
+}
+
+// tokenizeTemplate splits text into literal and tag tokens. Block and include
+// tags that sit alone on a line consume that line so they leave no blank lines
+// behind; raw keeps the consumed text for expansion.
+func tokenizeTemplate(text string) []templateToken {
+	var tokens []templateToken
+	pos := 0
+	for _, loc := range templateTagPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if start < pos {
+			continue
+		}
+		// ${{ ... }} belongs to CI expression syntax, not to beet.
+		if start > 0 && text[start-1] == '$' {
+			continue
+		}
+		tag := text[loc[2]:loc[3]]
+		line := strings.Count(text[:start], "\n") + 1
+
+		textEnd := start
+		next := end
+		standalone := false
+		if isBlockTag(tag) {
+			lineStart := strings.LastIndex(text[:start], "\n") + 1
+			lineEnd := strings.Index(text[end:], "\n")
+			if lineEnd < 0 {
+				lineEnd = len(text)
+			} else {
+				lineEnd += end
+			}
+			if lineStart >= pos && strings.TrimSpace(text[lineStart:start]) == "" && strings.TrimSpace(text[end:lineEnd]) == "" {
+				textEnd = lineStart
+				next = lineEnd
+				if next < len(text) {
+					next++
+				}
+				standalone = true
+			}
+		}
+
+		tokens = append(tokens, templateToken{text: text[pos:textEnd], raw: text[pos:textEnd]})
+		tokens = append(tokens, templateToken{raw: text[textEnd:next], tag: tag, isTag: true, standalone: standalone, line: line})
+		pos = next
+	}
+	tokens = append(tokens, templateToken{text: text[pos:], raw: text[pos:]})
+	return tokens
+}
+
+func (t *parsedTemplate) execute(values placeholderValues) (string, []string) {
+	r := &templateRun{values: values, seen: make(map[string]bool)}
+	var b strings.Builder
+	r.render(&b, t.nodes)
+	return b.String(), r.empty
+}
+
+type templateRun struct {
+	values placeholderValues
+	items  []string
+	empty  []string
+	seen   map[string]bool
+}
+
+func (r *templateRun) lookup(name string) string {
+	if name == "." {
+		if len(r.items) == 0 {
+			return ""
+		}
+		return r.items[len(r.items)-1]
+	}
+	return r.values[name]
+}
+
+func (r *templateRun) render(b *strings.Builder, nodes []templateNode) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case textNode:
+			b.WriteString(n.text)
+		case varNode:
+			value := r.lookup(n.name)
+			if strings.TrimSpace(value) == "" {
+				if n.hasDefault {
+					value = n.fallback
+				} else if n.name != "." && !r.seen[n.name] {
+					r.seen[n.name] = true
+					r.empty = append(r.empty, n.name)
+				}
+			}
+			b.WriteString(value)
+		case *ifNode:
+			if strings.TrimSpace(r.lookup(n.name)) != "" {
+				r.render(b, n.then)
+			} else {
+				r.render(b, n.otherwise)
+			}
+		case *eachNode:
+			items := listItems(r.lookup(n.name))
+			if len(items) == 0 {
+				r.render(b, n.otherwise)
+				continue
+			}
+			for _, item := range items {
+				r.items = append(r.items, item)
+				r.render(b, n.body)
+				r.items = r.items[:len(r.items)-1]
+			}
+		}
+	}
+}
+
+// listItems splits a placeholder value into list items: one per non-empty
+// line with bullet markers removed, or comma-separated for single-line values.
+func listItems(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(listBulletPattern.ReplaceAllString(line, "")))
+	}
+
+	if len(lines) == 1 && !listBulletPattern.MatchString(strings.TrimSpace(value)) && strings.Contains(lines[0], ",") {
+		var items []string
+		for _, part := range strings.Split(lines[0], ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				items = append(items, part)
+			}
+		}
+		return items
+	}
+	return lines
+}
+
+func renderTemplateText(name, text string, values placeholderValues, load partialLoader) (string, []string, error) {
+	parsed, err := parseTemplate(name, text, load)
+	if err != nil {
+		return "", nil, err
+	}
+	rendered, empty := parsed.execute(values)
+	return rendered, empty, nil
+}
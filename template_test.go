@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplateTextReportsEmpty(t *testing.T) {
+	template := "Goals: {{goals}}\nRisks: {{ risks }}\nAgain: {{risks}}\nIntent: {{intent}}\n"
+	values := placeholderValues{"intent": "ship", "goals": "fast"}
+
+	got, empty, err := renderTemplateText("custom.md", template, values, nil)
+	if err != nil {
+		t.Fatalf("renderTemplateText returned error: %v", err)
+	}
+	want := "Goals: fast\nRisks: \nAgain: \nIntent: ship\n"
+	if got != want {
+		t.Fatalf("renderTemplateText = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(empty, []string{"risks"}) {
+		t.Fatalf("empty placeholders = %v, want [risks]", empty)
+	}
+}
+
+func TestRenderTemplateTextConditionals(t *testing.T) {
+	template := "# Design\n{{#if risks}}\n## Risks\n{{risks}}\n{{else}}\nNo risks given.\n{{/if}}\nEnd\n"
+
+	got, empty, err := renderTemplateText("design.md", template, placeholderValues{"risks": "outage"}, nil)
+	if err != nil {
+		t.Fatalf("renderTemplateText returned error: %v", err)
+	}
+	if got != "# Design\n## Risks\noutage\nEnd\n" {
+		t.Fatalf("with risks = %q", got)
+	}
+	if len(empty) != 0 {
+		t.Fatalf("unexpected empty placeholders: %v", empty)
+	}
+
+	got, empty, err = renderTemplateText("design.md", template, placeholderValues{}, nil)
+	if err != nil {
+		t.Fatalf("renderTemplateText returned error: %v", err)
+	}
+	if got != "# Design\nNo risks given.\nEnd\n" {
+		t.Fatalf("without risks = %q", got)
+	}
+	if len(empty) != 0 {
+		t.Fatalf("condition-only placeholders should not be reported: %v", empty)
+	}
+}
+
+func TestRenderTemplateTextEachAndDefault(t *testing.T) {
+	template := "{{#each deliverables}}\n- [ ] {{.}}\n{{else}}\n- [ ] {{intent}}\n{{/each}}\nOwner: {{owner | default \"unassigned\"}}\n"
+
+	got, _, err := renderTemplateText("plan.md", template, placeholderValues{
+		"deliverables": "- api\n* cli\n3. docs",
+		"intent":       "ship",
+	}, nil)
+	if err != nil {
+		t.Fatalf("renderTemplateText returned error: %v", err)
+	}
+	want := "- [ ] api\n- [ ] cli\n- [ ] docs\nOwner: unassigned\n"
+	if got != want {
+		t.Fatalf("renderTemplateText = %q, want %q", got, want)
+	}
+
+	got, _, err = renderTemplateText("plan.md", template, placeholderValues{"intent": "ship", "owner": "sam"}, nil)
+	if err != nil {
+		t.Fatalf("renderTemplateText returned error: %v", err)
+	}
+	if got != "- [ ] ship\nOwner: sam\n" {
+		t.Fatalf("renderTemplateText empty list = %q", got)
+	}
+}
+
+func TestListItems(t *testing.T) {
+	cases := map[string][]string{
+		"":                 nil,
+		"go, typescript":   {"go", "typescript"},
+		"- a, b\n- c":      {"a, b", "c"},
+		"- only, one":      {"only, one"},
+		"1) first\n\n2) x": {"first", "x"},
+	}
+	for input, want := range cases {
+		if got := listItems(input); !reflect.DeepEqual(got, want) {
+			t.Fatalf("listItems(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestRenderTemplateTextKeepsUnknownBraces(t *testing.T) {
+	got, _, err := renderTemplateText("ci.md", "run: ${{ env.GO }}\n", placeholderValues{}, nil)
+	if err != nil {
+		t.Fatalf("renderTemplateText returned error: %v", err)
+	}
+	if got != "run: ${{ env.GO }}\n" {
+		t.Fatalf("renderTemplateText = %q", got)
+	}
+}
+
+func TestParseTemplateErrorsPointAtLine(t *testing.T) {
+	cases := map[string]string{
+		"line1\n{{#if goals}}\nbody\n":           "template prd.md:2: unclosed {{#if goals}}",
+		"a\nb\n{{/if}}\n":                        "template prd.md:3: unexpected {{/if}}",
+		"{{#each items}}\n{{/if}}\n":             "template prd.md:2: {{/if}} does not close {{#each items}} opened at line 1",
+		"{{#loop items}}{{/loop}}":               "template prd.md:1: unknown block {{#loop}}",
+		"{{#if}}{{/if}}":                         "template prd.md:1: {{#if}} needs exactly one placeholder name",
+		"x\n{{else}}\n":                          "template prd.md:2: {{else}} outside of a block",
+		"{{risks | upper}}":                      "template prd.md:1: unknown filter",
+		"{{risks | default none}}":               "template prd.md:1: default in {{risks | default none}} needs a quoted string",
+		"{{#if a}}\n{{else}}\n{{else}}\n{{/if}}": "template prd.md:3: duplicate {{else}}",
+	}
+	for input, want := range cases {
+		_, _, err := renderTemplateText("prd.md", input, placeholderValues{}, nil)
+		if err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error for %q = %q, want it to contain %q", input, err.Error(), want)
+		}
+	}
+}
+
+func mapPartials(partials map[string]string) partialLoader {
+	return func(name string) (string, error) {
+		text, ok := partials[name]
+		if !ok {
+			return "", fmt.Errorf("load partial %s: not found", name)
+		}
+		return text, nil
+	}
+}
+
+func TestRenderTemplateTextIncludesPartials(t *testing.T) {
+	load := mapPartials(map[string]string{
+		"header":          "# {{title}}\n{{> safety/preamble}}",
+		"safety/preamble": "Do no harm.\n",
+	})
+
+	got, _, err := renderTemplateText("prd.md", "{{> header}}\nBody\n", placeholderValues{"title": "PRD"}, load)
+	if err != nil {
+		t.Fatalf("renderTemplateText returned error: %v", err)
+	}
+	if got != "# PRD\nDo no harm.\nBody\n" {
+		t.Fatalf("renderTemplateText = %q", got)
+	}
+}
+
+func TestIncludeCycleDetected(t *testing.T) {
+	load := mapPartials(map[string]string{
+		"a": "{{> b}}\n",
+		"b": "x\n{{> a}}\n",
+	})
+
+	_, _, err := renderTemplateText("prd.md", "{{> a}}\n", placeholderValues{}, load)
+	if err == nil {
+		t.Fatalf("expected include cycle error")
+	}
+	want := "template partials/b.md:2: include cycle: prd.md -> partials/a.md -> partials/b.md -> partials/a.md"
+	if err.Error() != want {
+		t.Fatalf("error = %q, want %q", err.Error(), want)
+	}
+
+	if _, err := expandTemplate("prd.md", "{{> a}}\n", load); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Fatalf("expandTemplate should report cycle, got %v", err)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	load := mapPartials(map[string]string{"bad": "{{#if x}}\n"})
+
+	cases := map[string]string{
+		"{{> missing}}":   "template prd.md:1: load partial missing: not found",
+		"{{> ../secret}}": "template prd.md:1: invalid partial name",
+		"\n{{> bad}}":     "template partials/bad.md:1: unclosed {{#if x}}",
+	}
+	for input, want := range cases {
+		_, _, err := renderTemplateText("prd.md", input, placeholderValues{}, load)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("error for %q = %v, want %q", input, err, want)
+		}
+	}
+}
+
+func TestExpandTemplateKeepsDirectives(t *testing.T) {
+	load := mapPartials(map[string]string{"guidelines": "## Guidelines\n{{guidelines}}"})
+	text := "# PRD\n{{#if goals}}\n{{goals}}\n{{/if}}\n{{> guidelines}}\nEnd\n"
+
+	got, err := expandTemplate("prd.md", text, load)
+	if err != nil {
+		t.Fatalf("expandTemplate returned error: %v", err)
+	}
+	want := "# PRD\n{{#if goals}}\n{{goals}}\n{{/if}}\n## Guidelines\n{{guidelines}}\nEnd\n"
+	if got != want {
+		t.Fatalf("expandTemplate = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	diffContextLines = 3
+	// maxDiffCells bounds the LCS table; larger changes fall back to
+	// replacing the whole differing middle.
+	maxDiffCells  = 16 << 20
+	diffStatWidth = 40
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// noNewlineMarker follows a last line that lacks its newline, as in git. It
+// is kept on the line itself so a newline-only change shows up as a change.
+const noNewlineMarker = "\\ No newline at end of file"
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	trimmed, ok := strings.CutSuffix(text, "\n")
+	lines := strings.Split(trimmed, "\n")
+	if !ok {
+		lines[len(lines)-1] += "\n" + noNewlineMarker
+	}
+	return lines
+}
+
+// diffLines returns an edit script turning a into b, based on a longest
+// common subsequence of lines after trimming the common prefix and suffix.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+type diffPrinter struct {
+	w     io.Writer
+	color bool
+}
+
+func (p diffPrinter) printf(color, format string, args ...interface{}) error {
+	text := fmt.Sprintf(format, args...)
+	if p.color && color != "" {
+		text = color + text + ansiReset
+	}
+	_, err := fmt.Fprintln(p.w, text)
+	return err
+}
+
+// unifiedDiff writes a unified diff of oldText and newText and returns the
+// number of added and removed lines. An empty oldName marks a new file.
+func (p diffPrinter) unifiedDiff(oldName, newName, oldText, newText string) (added, removed int, err error) {
+	ops := diffLines(splitDiffLines(oldText), splitDiffLines(newText))
+	added, removed = countChanges(ops)
+	if added == 0 && removed == 0 {
+		return 0, 0, nil
+	}
+	// Keep the first write error and skip the rest.
+	printf := func(color, format string, args ...interface{}) {
+		if err == nil {
+			err = p.printf(color, format, args...)
+		}
+	}
+
+	if oldName == "" {
+		printf(ansiBold, "--- /dev/null")
+	} else {
+		printf(ansiBold, "--- a/%s", oldName)
+	}
+	printf(ansiBold, "+++ b/%s", newName)
+
+	for _, h := range diffHunks(ops) {
+		oldStart, newStart := h.oldStart, h.newStart
+		if h.oldLines > 0 {
+			oldStart++
+		}
+		if h.newLines > 0 {
+			newStart++
+		}
+		printf(ansiCyan, "@@ -%d,%d +%d,%d @@", oldStart, h.oldLines, newStart, h.newLines)
+		for _, op := range h.ops {
+			line, noNewline := strings.CutSuffix(op.line, "\n"+noNewlineMarker)
+			switch op.kind {
+			case '-':
+				printf(ansiRed, "-%s", line)
+			case '+':
+				printf(ansiGreen, "+%s", line)
+			default:
+				printf("", " %s", line)
+			}
+			if noNewline {
+				printf("", "%s", noNewlineMarker)
+			}
+		}
+	}
+	return added, removed, err
+}
+
+func countChanges(ops []diffOp) (added, removed int) {
+	for _, op := range ops {
+		switch op.kind {
+		case '+':
+			added++
+		case '-':
+			removed++
+		}
+	}
+	return added, removed
+}
+
+type diffHunk struct {
+	oldStart, oldLines int
+	newStart, newLines int
+	ops                []diffOp
+}
+
+// diffHunks groups changes with diffContextLines of context, merging hunks
+// whose context would overlap.
+func diffHunks(ops []diffOp) []diffHunk {
+	var hunks []diffHunk
+	oldLine, newLine := 0, 0
+	oldAt := make([]int, len(ops))
+	newAt := make([]int, len(ops))
+	for i, op := range ops {
+		oldAt[i], newAt[i] = oldLine, newLine
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(i-diffContextLines, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContextLines {
+				end = min(end+diffContextLines, len(ops))
+				break
+			}
+			end = run
+		}
+
+		h := diffHunk{oldStart: oldAt[start], newStart: newAt[start], ops: ops[start:end]}
+		for _, op := range h.ops {
+			if op.kind != '+' {
+				h.oldLines++
+			}
+			if op.kind != '-' {
+				h.newLines++
+			}
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+type diffStat struct {
+	name           string
+	added, removed int
+}
+
+func (p diffPrinter) stat(stats []diffStat) error {
+	width, most := 0, 0
+	for _, s := range stats {
+		width = max(width, len(s.name))
+		most = max(most, s.added+s.removed)
+	}
+	added, removed := 0, 0
+	for _, s := range stats {
+		plus, minus := s.added, s.removed
+		if most > diffStatWidth {
+			plus = scaleStat(s.added, most)
+			minus = scaleStat(s.removed, most)
+		}
+		bar := strings.Repeat("+", plus) + strings.Repeat("-", minus)
+		if p.color {
+			bar = ansiGreen + strings.Repeat("+", plus) + ansiRed + strings.Repeat("-", minus) + ansiReset
+		}
+		if _, err := fmt.Fprintf(p.w, " %-*s | %d %s\n", width, s.name, s.added+s.removed, bar); err != nil {
+			return err
+		}
+		added += s.added
+		removed += s.removed
+	}
+	files := "files"
+	if len(stats) == 1 {
+		files = "file"
+	}
+	_, err := fmt.Fprintf(p.w, " %d %s changed, %d insertions(+), %d deletions(-)\n", len(stats), files, added, removed)
+	return err
+}
+
+func scaleStat(n, most int) int {
+	if n == 0 {
+		return 0
+	}
+	return max(n*diffStatWidth/most, 1)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	updated := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+
+	var buf bytes.Buffer
+	added, removed, err := diffPrinter{w: &buf}.unifiedDiff("OUT.md", "OUT.md", old, updated)
+	if err != nil || added != 2 || removed != 1 {
+		t.Fatalf("got +%d -%d, %v, want +2 -1", added, removed, err)
+	}
+	want := `--- a/OUT.md
++++ b/OUT.md
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if buf.String() != want {
+		t.Fatalf("diff mismatch:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestUnifiedDiffNewFileAndColor(t *testing.T) {
+	var buf bytes.Buffer
+	added, removed, err := diffPrinter{w: &buf, color: true}.unifiedDiff("", "NEW.md", "", "one\ntwo\n")
+	if err != nil || added != 2 || removed != 0 {
+		t.Fatalf("got +%d -%d, %v, want +2 -0", added, removed, err)
+	}
+	out := buf.String()
+	for _, want := range []string{"--- /dev/null", "+++ b/NEW.md", "@@ -0,0 +1,2 @@", ansiGreen + "+one" + ansiReset} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%q", want, out)
+		}
+	}
+}
+
+func TestUnifiedDiffUnchanged(t *testing.T) {
+	var buf bytes.Buffer
+	if added, removed, err := (diffPrinter{w: &buf}).unifiedDiff("a", "a", "same\n", "same\n"); err != nil || added != 0 || removed != 0 || buf.Len() != 0 {
+		t.Fatalf("got +%d -%d and %q for identical text", added, removed, buf.String())
+	}
+}
+
+func TestUnifiedDiffMissingNewline(t *testing.T) {
+	var buf bytes.Buffer
+	added, removed, err := diffPrinter{w: &buf}.unifiedDiff("OUT.md", "OUT.md", "a\nb\n", "a\nb")
+	if err != nil || added != 1 || removed != 1 {
+		t.Fatalf("got +%d -%d, %v, want +1 -1", added, removed, err)
+	}
+	want := "--- a/OUT.md\n+++ b/OUT.md\n@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n"
+	if buf.String() != want {
+		t.Fatalf("diff mismatch:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestDiffPrinterReportsWriteErrors(t *testing.T) {
+	p := diffPrinter{w: failingWriter{}}
+	if _, _, err := p.unifiedDiff("a", "a", "old\n", "new\n"); err == nil || err.Error() != "disk full" {
+		t.Fatalf("unifiedDiff error = %v", err)
+	}
+	if err := p.stat([]diffStat{{name: "a", added: 1}}); err == nil {
+		t.Fatal("stat: expected the write error")
+	}
+}
+
+func TestDiffStat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (diffPrinter{w: &buf}).stat([]diffStat{
+		{name: "WORK_PROMPT.md", added: 2, removed: 1},
+		{name: "PLAN.md", added: 80},
+	}); err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	want := " WORK_PROMPT.md | 3 +-\n" +
+		" PLAN.md        | 80 " + strings.Repeat("+", diffStatWidth) + "\n" +
+		" 2 files changed, 82 insertions(+), 1 deletions(-)\n"
+	if buf.String() != want {
+		t.Fatalf("stat mismatch:\n%q\nwant:\n%q", buf.String(), want)
+	}
+}
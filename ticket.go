@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+import "gopkg.in/yaml.v3"
+
+// ticket is the part of an issue export that beet turns into an intent.
+type ticket struct {
+	key        string
+	title      string
+	url        string
+	body       string
+	labels     []string
+	acceptance string
+}
+
+var (
+	acceptanceHeadingPattern = regexp.MustCompile(`(?im)^#{1,6}\s+(acceptance( criteria)?|definition of done)\s*$`)
+	jiraHeadingPattern       = regexp.MustCompile(`(?m)^h([1-6])\.\s+`)
+	htmlBreakPattern         = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</h[1-6]>|</tr>`)
+	htmlHeadingPattern       = regexp.MustCompile(`(?i)<h([1-6])[^>]*>`)
+	htmlListItemPattern      = regexp.MustCompile(`(?i)<li[^>]*>`)
+	htmlTagPattern           = regexp.MustCompile(`<[^>]+>`)
+	blankLinesPattern        = regexp.MustCompile(`\n{3,}`)
+)
+
+// loadTicket reads a GitHub issue JSON export or a Jira XML/CSV export. The
+// format is detected from the content, falling back to the extension for CSV.
+func loadTicket(path string) (ticket, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ticket{}, fmt.Errorf("read ticket: %w", err)
+	}
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+
+	var t ticket
+	switch {
+	case len(trimmed) == 0:
+		return ticket{}, fmt.Errorf("ticket %s is empty", path)
+	case trimmed[0] == '{' || trimmed[0] == '[':
+		t, err = parseGitHubIssue(trimmed)
+	case trimmed[0] == '<':
+		t, err = parseJiraXML(trimmed)
+	case strings.EqualFold(filepath.Ext(path), ".csv") || bytes.Contains(firstLine(trimmed), []byte("Summary")):
+		t, err = parseJiraCSV(trimmed)
+	default:
+		return ticket{}, fmt.Errorf("ticket %s: unrecognized format; expected GitHub issue JSON or Jira XML/CSV", path)
+	}
+	if err != nil {
+		return ticket{}, fmt.Errorf("ticket %s: %w", path, err)
+	}
+	if strings.TrimSpace(t.title) == "" && strings.TrimSpace(t.body) == "" {
+		return ticket{}, fmt.Errorf("ticket %s has no title or description", path)
+	}
+	logVerbose("loaded ticket %q from %s", firstNonEmpty(t.key, t.title), path)
+	return t, nil
+}
+
+func firstLine(data []byte) []byte {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[:i]
+	}
+	return data
+}
+
+type githubIssue struct {
+	Number  int                `json:"number"`
+	Title   string             `json:"title"`
+	Body    string             `json:"body"`
+	URL     string             `json:"url"`
+	HTMLURL string             `json:"html_url"`
+	Labels  []githubIssueLabel `json:"labels"`
+}
+
+// githubIssueLabel accepts both `gh issue view --json labels` objects and
+// plain label names.
+type githubIssueLabel string
+
+func (l *githubIssueLabel) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*l = githubIssueLabel(name)
+		return nil
+	}
+	var obj struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*l = githubIssueLabel(obj.Name)
+	return nil
+}
+
+func parseGitHubIssue(data []byte) (ticket, error) {
+	var issues []githubIssue
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &issues); err != nil {
+			return ticket{}, fmt.Errorf("parse GitHub issue JSON: %w", err)
+		}
+	} else {
+		var issue githubIssue
+		if err := json.Unmarshal(data, &issue); err != nil {
+			return ticket{}, fmt.Errorf("parse GitHub issue JSON: %w", err)
+		}
+		issues = append(issues, issue)
+	}
+	if len(issues) != 1 {
+		return ticket{}, fmt.Errorf("export holds %d issues; export a single issue", len(issues))
+	}
+
+	issue := issues[0]
+	t := ticket{
+		title: strings.TrimSpace(issue.Title),
+		url:   firstNonEmpty(issue.HTMLURL, issue.URL),
+		body:  strings.TrimSpace(strings.ReplaceAll(issue.Body, "\r\n", "\n")),
+	}
+	if issue.Number > 0 {
+		t.key = fmt.Sprintf("#%d", issue.Number)
+	}
+	for _, label := range issue.Labels {
+		if name := strings.TrimSpace(string(label)); name != "" {
+			t.labels = append(t.labels, name)
+		}
+	}
+	return t, nil
+}
+
+type jiraExport struct {
+	Items []jiraItem `xml:"channel>item"`
+}
+
+type jiraItem struct {
+	Key          string            `xml:"key"`
+	Summary      string            `xml:"summary"`
+	Title        string            `xml:"title"`
+	Link         string            `xml:"link"`
+	Description  string            `xml:"description"`
+	Labels       []string          `xml:"labels>label"`
+	CustomFields []jiraCustomField `xml:"customfields>customfield"`
+}
+
+type jiraCustomField struct {
+	Name   string   `xml:"customfieldname"`
+	Values []string `xml:"customfieldvalues>customfieldvalue"`
+}
+
+func parseJiraXML(data []byte) (ticket, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	var export jiraExport
+	if err := dec.Decode(&export); err != nil {
+		return ticket{}, fmt.Errorf("parse Jira XML: %w", err)
+	}
+	if len(export.Items) != 1 {
+		return ticket{}, fmt.Errorf("export holds %d issues; export a single issue", len(export.Items))
+	}
+
+	item := export.Items[0]
+	t := ticket{
+		key:    strings.TrimSpace(item.Key),
+		title:  strings.TrimSpace(firstNonEmpty(item.Summary, item.Title)),
+		url:    strings.TrimSpace(item.Link),
+		body:   htmlToText(item.Description),
+		labels: trimmedNonEmpty(item.Labels),
+	}
+	for _, field := range item.CustomFields {
+		if isAcceptanceField(field.Name) {
+			t.acceptance = htmlToText(strings.Join(field.Values, "\n"))
+		}
+	}
+	return t, nil
+}
+
+func parseJiraCSV(data []byte) (ticket, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return ticket{}, fmt.Errorf("parse Jira CSV: %w", err)
+	}
+	if len(records) != 2 {
+		return ticket{}, fmt.Errorf("export holds %d issues; export a single issue", max(len(records)-1, 0))
+	}
+
+	header, row := records[0], records[1]
+	var t ticket
+	for i, column := range header {
+		if i >= len(row) {
+			break
+		}
+		value := strings.TrimSpace(row[i])
+		name := strings.ToLower(strings.TrimSpace(column))
+		switch {
+		case name == "summary":
+			t.title = value
+		case name == "issue key":
+			t.key = value
+		case name == "description":
+			t.body = jiraWikiToMarkdown(value)
+		case name == "labels":
+			// Jira repeats the Labels column once per label.
+			if value != "" {
+				t.labels = append(t.labels, value)
+			}
+		case isAcceptanceField(name):
+			t.acceptance = jiraWikiToMarkdown(value)
+		}
+	}
+	return t, nil
+}
+
+func isAcceptanceField(name string) bool {
+	return strings.Contains(strings.ToLower(name), "acceptance criteria")
+}
+
+func jiraWikiToMarkdown(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = jiraHeadingPattern.ReplaceAllStringFunc(text, func(m string) string {
+		return strings.Repeat("#", int(m[1]-'0')) + " "
+	})
+	return strings.TrimSpace(text)
+}
+
+// htmlToText flattens the rendered HTML of Jira fields into Markdown-ish text,
+// keeping headings and list items so the intent parser can still find
+// sections.
+func htmlToText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = htmlHeadingPattern.ReplaceAllStringFunc(text, func(m string) string {
+		level := htmlHeadingPattern.FindStringSubmatch(m)[1]
+		return "\n" + strings.Repeat("#", int(level[0]-'0')) + " "
+	})
+	text = htmlListItemPattern.ReplaceAllString(text, "\n- ")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = strings.ReplaceAll(html.UnescapeString(text), "\u00a0", " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
+}
+
+func trimmedNonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// intentText renders the ticket as an intent document: front matter for the
+// ticket metadata, the title as a heading, then the description. Acceptance
+// criteria from a separate field become their own section unless the
+// description already has one.
+func (t ticket) intentText() (string, error) {
+	front := struct {
+		Ticket string   `yaml:"ticket,omitempty"`
+		Title  string   `yaml:"title,omitempty"`
+		URL    string   `yaml:"url,omitempty"`
+		Labels []string `yaml:"labels,omitempty"`
+	}{t.key, t.title, t.url, t.labels}
+	meta, err := yaml.Marshal(front)
+	if err != nil {
+		return "", fmt.Errorf("encode ticket front matter: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n" + string(meta) + "---\n\n")
+	if t.title != "" {
+		heading := t.title
+		if t.key != "" {
+			heading = t.key + ": " + t.title
+		}
+		b.WriteString("# " + heading + "\n\n")
+	}
+	if t.body != "" {
+		b.WriteString(t.body + "\n\n")
+	}
+	if t.acceptance != "" && !acceptanceHeadingPattern.MatchString(t.body) {
+		b.WriteString("## Acceptance Criteria\n\n" + t.acceptance + "\n")
+	}
+	return strings.TrimSpace(b.String()), nil
+}
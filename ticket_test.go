@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTicket(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write ticket: %v", err)
+	}
+	return path
+}
+
+func TestLoadTicketGitHubIssue(t *testing.T) {
+	path := writeTicket(t, "issue.json", `{
+  "number": 42,
+  "title": "Add export",
+  "body": "Users want CSV.\r\n\r\n## Acceptance criteria\r\n- exports CSV\r\n",
+  "url": "https://github.com/o/r/issues/42",
+  "labels": [{"name": "feature"}, {"name": "ui"}]
+}`)
+	got, err := loadTicket(path)
+	if err != nil {
+		t.Fatalf("loadTicket: %v", err)
+	}
+	want := ticket{key: "#42", title: "Add export", url: "https://github.com/o/r/issues/42", body: "Users want CSV.\n\n## Acceptance criteria\n- exports CSV", labels: []string{"feature", "ui"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ticket = %+v, want %+v", got, want)
+	}
+
+	text, err := got.intentText()
+	if err != nil {
+		t.Fatalf("intentText: %v", err)
+	}
+	doc, err := parseIntentDocument(text)
+	if err != nil {
+		t.Fatalf("parseIntentDocument: %v", err)
+	}
+	fields := intentPlaceholders(doc)
+	if fields["title"] != "Add export" || fields["ticket"] != "#42" || fields["labels"] != "- feature\n- ui" {
+		t.Fatalf("front matter fields = %v", fields)
+	}
+	if fields["acceptance_criteria"] != "- exports CSV" {
+		t.Fatalf("acceptance_criteria = %q", fields["acceptance_criteria"])
+	}
+	if fields["intent"] != "# #42: Add export\n\nUsers want CSV." {
+		t.Fatalf("intent = %q", fields["intent"])
+	}
+}
+
+func TestLoadTicketJiraXML(t *testing.T) {
+	path := writeTicket(t, "export.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="0.92"><channel><title>Jira</title>
+<item>
+  <title>[PROJ-7] Fix login</title>
+  <link>https://jira.example.com/browse/PROJ-7</link>
+  <key id="1">PROJ-7</key>
+  <summary>Fix login</summary>
+  <description>&lt;p&gt;Login fails&amp;nbsp;on Safari.&lt;/p&gt;&lt;ul&gt;&lt;li&gt;iOS 17&lt;/li&gt;&lt;/ul&gt;</description>
+  <labels><label>bug</label><label>auth</label></labels>
+  <customfields>
+    <customfield id="customfield_1"><customfieldname>Acceptance Criteria</customfieldname>
+      <customfieldvalues><customfieldvalue>&lt;p&gt;Safari users can log in&lt;/p&gt;</customfieldvalue></customfieldvalues>
+    </customfield>
+  </customfields>
+</item></channel></rss>`)
+	got, err := loadTicket(path)
+	if err != nil {
+		t.Fatalf("loadTicket: %v", err)
+	}
+	want := ticket{key: "PROJ-7", title: "Fix login", url: "https://jira.example.com/browse/PROJ-7", body: "Login fails on Safari.\n\n- iOS 17", labels: []string{"bug", "auth"}, acceptance: "Safari users can log in"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ticket = %#v, want %#v", got, want)
+	}
+
+	text, err := got.intentText()
+	if err != nil {
+		t.Fatalf("intentText: %v", err)
+	}
+	doc, err := parseIntentDocument(text)
+	if err != nil {
+		t.Fatalf("parseIntentDocument: %v", err)
+	}
+	if doc.fields["acceptance_criteria"] != "Safari users can log in" {
+		t.Fatalf("fields = %v", doc.fields)
+	}
+}
+
+func TestLoadTicketJiraCSV(t *testing.T) {
+	path := writeTicket(t, "export.csv", "Summary,Issue key,Description,Labels,Labels,Custom field (Acceptance Criteria)\n"+
+		"Fix login,PROJ-7,\"Login fails.\nh2. Notes\nSafari only\",bug,auth,Safari users can log in\n")
+	got, err := loadTicket(path)
+	if err != nil {
+		t.Fatalf("loadTicket: %v", err)
+	}
+	want := ticket{key: "PROJ-7", title: "Fix login", body: "Login fails.\n## Notes\nSafari only", labels: []string{"bug", "auth"}, acceptance: "Safari users can log in"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ticket = %#v, want %#v", got, want)
+	}
+}
+
+func TestLoadTicketErrors(t *testing.T) {
+	cases := map[string]string{
+		"two.json":    `[{"title": "a"}, {"title": "b"}]`,
+		"notes.txt":   "just some notes",
+		"empty.json":  `{"labels": []}`,
+		"rows.csv":    "Summary\na\nb\n",
+		"broken.json": `{"title": `,
+	}
+	wants := map[string]string{
+		"two.json":    "export holds 2 issues",
+		"notes.txt":   "unrecognized format",
+		"empty.json":  "has no title or description",
+		"rows.csv":    "export holds 2 issues",
+		"broken.json": "parse GitHub issue JSON",
+	}
+	for name, content := range cases {
+		_, err := loadTicket(writeTicket(t, name, content))
+		if err == nil || !strings.Contains(err.Error(), wants[name]) {
+			t.Fatalf("%s: expected %q error, got %v", name, wants[name], err)
+		}
+	}
+}
+
+func TestParseIntentFromTicketLeads(t *testing.T) {
+	path := writeTicket(t, "issue.json", `{"title": "Add export", "body": "Users want CSV."}`)
+	intent, err := parseIntent([]string{"keep", "it", "small"}, intentOptions{ticket: path})
+	if err != nil {
+		t.Fatalf("parseIntent: %v", err)
+	}
+	if !strings.HasPrefix(intent, "---\ntitle: Add export\n---\n") || !strings.HasSuffix(intent, "Users want CSV.\n\nkeep it small") {
+		t.Fatalf("intent = %q", intent)
+	}
+}